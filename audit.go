@@ -0,0 +1,268 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AuditOperation is the kind of change recorded in the audit table.
+type AuditOperation string
+
+const (
+	AuditInsert  AuditOperation = "insert"
+	AuditUpdate  AuditOperation = "update"
+	AuditDelete  AuditOperation = "delete"
+	AuditRestore AuditOperation = "restore"
+)
+
+// AuditEntry is a change of a row recorded in the audit table.
+type AuditEntry struct {
+	ID    int64
+	Table string
+
+	// Key is the primary key of the row formatted as text.
+	Key       string
+	Operation AuditOperation
+	Time      time.Time
+
+	// Actor is the value assigned to the context with WithActor when the change was made.
+	Actor string
+
+	// Before and After are the JSON encoded columns of the row before and after the change,
+	// keyed by the column names. Before is nil for inserts and After is nil for deletes, except
+	// soft deletes that keep the marked row.
+	Before json.RawMessage
+	After  json.RawMessage
+}
+
+type auditRow struct {
+	ID        int64
+	TableName string
+	RecordKey string
+	Operation string
+	Time      time.Time
+	Actor     string
+	OldValue  []byte
+	NewValue  []byte
+}
+
+type actorKey struct{}
+
+// WithActor returns a context that records actor as the author of the changes made with it in
+// the audit tables.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// AuditMigration returns a migration that creates the audit table with the name. The same table
+// can be shared by several repositories.
+func AuditMigration(table string) Migration {
+	return func(ctx context.Context, db *sqlx.DB) error {
+		q := fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s (
+				ID INTEGER PRIMARY KEY AUTOINCREMENT,
+				TableName TEXT NOT NULL,
+				RecordKey TEXT NOT NULL,
+				Operation TEXT NOT NULL,
+				Time DATETIME NOT NULL,
+				Actor TEXT NOT NULL,
+				OldValue TEXT,
+				NewValue TEXT
+			);
+
+			CREATE INDEX IF NOT EXISTS %s_Record ON %s (TableName, RecordKey, ID);
+		`, table, table, table)
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			return fmt.Errorf("cannot create audit table: %w", err)
+		}
+		return nil
+	}
+}
+
+// history returns the changes recorded for the key in chronological order.
+func (s store[T]) history(ctx context.Context, key any) ([]*AuditEntry, error) {
+	if s.cnf.Audit == "" {
+		return nil, fmt.Errorf("audit is not configured for table %s", s.cnf.Table)
+	}
+	key, err := s.keyValue(key)
+	if err != nil {
+		return nil, err
+	}
+	tenant, err := s.tenantValue(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	q := fmt.Sprintf("SELECT ID, TableName, RecordKey, Operation, Time, Actor, OldValue, NewValue FROM %s WHERE TableName = ? AND RecordKey = ? ORDER BY ID", s.cnf.Audit)
+	s.log(ctx, "History", q, slog.Any("key", key))
+	var rows []*auditRow
+	if err := sqlx.SelectContext(ctx, s.ext, &rows, q, s.cnf.Table, fmt.Sprint(key)); err != nil {
+		return nil, fmt.Errorf("cannot execute query: %w", err)
+	}
+
+	entries := make([]*AuditEntry, 0, len(rows))
+	for _, row := range rows {
+		if tenant != nil {
+			owned, err := s.auditOwned(row, tenant)
+			if err != nil {
+				return nil, err
+			}
+			if !owned {
+				continue
+			}
+		}
+		entries = append(entries, &AuditEntry{
+			ID:        row.ID,
+			Table:     row.TableName,
+			Key:       row.RecordKey,
+			Operation: AuditOperation(row.Operation),
+			Time:      row.Time,
+			Actor:     row.Actor,
+			Before:    row.OldValue,
+			After:     row.NewValue,
+		})
+	}
+	return entries, nil
+}
+
+// auditOwned reports if any of the versions of the row recorded in the audit entry belong to
+// the tenant, comparing the tenant column encoded as it is in the audit.
+func (s store[T]) auditOwned(row *auditRow, tenant any) (bool, error) {
+	value, err := auditValue(tenant)
+	if err != nil {
+		return false, err
+	}
+	expected, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("cannot encode tenant: %w", err)
+	}
+	for _, encoded := range [][]byte{row.OldValue, row.NewValue} {
+		if encoded == nil {
+			continue
+		}
+		var cols map[string]json.RawMessage
+		if err := json.Unmarshal(encoded, &cols); err != nil {
+			return false, fmt.Errorf("cannot decode audit value: %w", err)
+		}
+		if bytes.Equal(cols[s.cnf.Tenant], expected) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// loadStored reads the row with the key as it is stored, including soft deleted rows and without
+// running the load hooks. It returns nil if the key does not exist.
+func (tx *Tx[T]) loadStored(ctx context.Context, key any) (*T, error) {
+	s := tx.store()
+	s.withDeleted = true
+	var model T
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE %s", s.selectCols(), tx.cnf.Table, s.pkWhere())
+	s.log(ctx, "loadStored", q)
+	if err := tx.shared.GetContext(ctx, &model, q, s.keyArgs(key)...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot execute query: %w", err)
+	}
+	return &model, nil
+}
+
+// auditBefore reads the stored version of the row with the key if the audit is enabled.
+func (tx *Tx[T]) auditBefore(ctx context.Context, key any) (*T, error) {
+	if tx.cnf.Audit == "" {
+		return nil, nil
+	}
+	return tx.loadStored(ctx, key)
+}
+
+// audit records a change in the audit table if it is enabled. Before and after can be nil when
+// the row did not exist before or after the change.
+func (tx *Tx[T]) audit(ctx context.Context, op AuditOperation, key any, before, after *T) error {
+	if tx.cnf.Audit == "" {
+		return nil
+	}
+
+	oldValue, err := tx.encodeAudit(before)
+	if err != nil {
+		return err
+	}
+	newValue, err := tx.encodeAudit(after)
+	if err != nil {
+		return err
+	}
+
+	q := fmt.Sprintf("INSERT INTO %s (TableName, RecordKey, Operation, Time, Actor, OldValue, NewValue) VALUES (?, ?, ?, ?, ?, ?, ?)", tx.cnf.Audit)
+	tx.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "Tx.audit"), slog.String("q", q), slog.Any("key", key))
+	if _, err := tx.shared.execPrepared(ctx, q, tx.cnf.Table, fmt.Sprint(key), string(op), tx.cnf.Clock(), actorFromContext(ctx), oldValue, newValue); err != nil {
+		return fmt.Errorf("cannot record audit: %w", err)
+	}
+	return nil
+}
+
+// encodeAudit encodes the columns of the model as a JSON object keyed by the column names, so
+// the audit matches the stored row. It returns nil if there is no model.
+func (tx *Tx[T]) encodeAudit(model *T) (any, error) {
+	if model == nil {
+		return nil, nil
+	}
+	cols, values := listCols(tx.shared.db, model)
+	row := make(map[string]any, len(cols))
+	for i, col := range cols {
+		value, err := auditValue(values[i])
+		if err != nil {
+			return nil, err
+		}
+		row[col] = value
+	}
+	encoded, err := json.Marshal(row)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode audit value: %w", err)
+	}
+	return string(encoded), nil
+}
+
+// auditValue returns the value of a column as it is sent to the database, for the types that
+// implement driver.Valuer.
+func auditValue(value any) (any, error) {
+	valuer, ok := value.(driver.Valuer)
+	if !ok {
+		return value, nil
+	}
+	if v := reflect.ValueOf(valuer); v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil, nil
+	}
+	v, err := valuer.Value()
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode audit value: %w", err)
+	}
+	return v, nil
+}
+
+// auditAfter records a change in the audit table reading the new version of the row from the
+// database, for the operations that do not have the full model available.
+func (tx *Tx[T]) auditAfter(ctx context.Context, op AuditOperation, key any, before *T) error {
+	if tx.cnf.Audit == "" {
+		return nil
+	}
+	after, err := tx.loadStored(ctx, key)
+	if err != nil {
+		return err
+	}
+	return tx.audit(ctx, op, key, before, after)
+}
@@ -0,0 +1,227 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var builderOperators = map[string]bool{
+	"=":      true,
+	"!=":     true,
+	"<":      true,
+	"<=":     true,
+	">":      true,
+	">=":     true,
+	"IN":     true,
+	"NOT IN": true,
+	"LIKE":   true,
+}
+
+// Builder composes parameterized queries against the table of a repository. Column names are
+// checked against the model and any mistake is returned when the query is executed, before
+// reaching the database.
+type Builder[T any] struct {
+	repo   *RepoGeneric[T]
+	cols   map[string]bool
+	conds  []string
+	args   []any
+	orders []string
+	limit  int
+	offset int
+	err    error
+}
+
+// Select starts a new query builder for the table of the repository.
+func (repo *RepoGeneric[T]) Select() *Builder[T] {
+	var single T
+	cols, _ := listCols(repo.db, single)
+	b := &Builder[T]{
+		repo: repo,
+		cols: make(map[string]bool),
+	}
+	for _, col := range cols {
+		b.cols[col] = true
+	}
+	return b
+}
+
+func (b *Builder[T]) checkCol(col string) bool {
+	if b.err != nil {
+		return false
+	}
+	if !b.cols[col] {
+		b.err = fmt.Errorf("unknown column %q in table %s", col, b.repo.cnf.Table)
+		return false
+	}
+	return true
+}
+
+// Filter adds a condition comparing the column with the value. Supported operators are
+// =, !=, <, <=, >, >=, IN, NOT IN and LIKE. IN operators expect a slice as value.
+func (b *Builder[T]) Filter(col, op string, value any) *Builder[T] {
+	if !b.checkCol(col) {
+		return b
+	}
+	op = strings.ToUpper(op)
+	if !builderOperators[op] {
+		b.err = fmt.Errorf("unknown operator %q", op)
+		return b
+	}
+
+	if op == "IN" || op == "NOT IN" {
+		b.conds = append(b.conds, fmt.Sprintf("%s %s (?)", col, op))
+	} else {
+		b.conds = append(b.conds, fmt.Sprintf("%s %s ?", col, op))
+	}
+	b.args = append(b.args, value)
+	return b
+}
+
+// FilterNull adds a condition requiring the column to be NULL.
+func (b *Builder[T]) FilterNull(col string) *Builder[T] {
+	if b.checkCol(col) {
+		b.conds = append(b.conds, col+" IS NULL")
+	}
+	return b
+}
+
+// FilterNotNull adds a condition requiring the column to not be NULL.
+func (b *Builder[T]) FilterNotNull(col string) *Builder[T] {
+	if b.checkCol(col) {
+		b.conds = append(b.conds, col+" IS NOT NULL")
+	}
+	return b
+}
+
+// Order sorts the results by the column. Prefix the column with a minus sign to sort in
+// descending order. It can be called multiple times to sort by several columns.
+func (b *Builder[T]) Order(col string) *Builder[T] {
+	dir := "ASC"
+	if strings.HasPrefix(col, "-") {
+		col = col[1:]
+		dir = "DESC"
+	}
+	if b.checkCol(col) {
+		b.orders = append(b.orders, col+" "+dir)
+	}
+	return b
+}
+
+// Limit sets the maximum number of results.
+func (b *Builder[T]) Limit(limit int) *Builder[T] {
+	b.limit = limit
+	return b
+}
+
+// Offset skips the first results.
+func (b *Builder[T]) Offset(offset int) *Builder[T] {
+	b.offset = offset
+	return b
+}
+
+func (b *Builder[T]) compile(ctx context.Context, fields string, paginate bool) (string, []any, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+
+	where, args, err := b.repo.store().where(ctx, b.conds, b.args)
+	if err != nil {
+		return "", nil, err
+	}
+	q := fmt.Sprintf("SELECT %s FROM %s%s", fields, b.repo.cnf.Table, where)
+	if paginate {
+		if len(b.orders) > 0 {
+			q += " ORDER BY " + strings.Join(b.orders, ", ")
+		}
+		if b.limit > 0 {
+			q += fmt.Sprintf(" LIMIT %d", b.limit)
+		} else if b.offset > 0 {
+			q += " LIMIT -1"
+		}
+		if b.offset > 0 {
+			q += fmt.Sprintf(" OFFSET %d", b.offset)
+		}
+	}
+
+	q, args, err = sqlx.In(q, args...)
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot prepare sql statement: %w", err)
+	}
+	return q, args, nil
+}
+
+func (b *Builder[T]) selectCols() string {
+	var single T
+	cols, _ := listCols(b.repo.db, single)
+	return strings.Join(cols, ",")
+}
+
+// Fetch runs the query and returns all the results.
+func (b *Builder[T]) Fetch(ctx context.Context) ([]*T, error) {
+	q, args, err := b.compile(ctx, b.selectCols(), true)
+	if err != nil {
+		return nil, err
+	}
+	b.repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "Builder.Fetch"), slog.String("q", q))
+	var models []*T
+	if err := b.repo.db.SelectContext(ctx, &models, q, args...); err != nil {
+		return nil, fmt.Errorf("cannot execute query: %w", err)
+	}
+	if err := runAfterLoad(ctx, b.repo.cnf.Hooks, models...); err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+// First runs the query and returns the first result. It returns sql.ErrNoRows if nothing matches.
+func (b *Builder[T]) First(ctx context.Context) (*T, error) {
+	first := *b
+	first.limit = 1
+	q, args, err := first.compile(ctx, b.selectCols(), true)
+	if err != nil {
+		return nil, err
+	}
+	b.repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "Builder.First"), slog.String("q", q))
+	var model T
+	if err := b.repo.db.GetContext(ctx, &model, q, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("cannot execute query: %w", err)
+	}
+	if err := runAfterLoad(ctx, b.repo.cnf.Hooks, &model); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// Count returns the number of rows matching the filters. Order, limit and offset are ignored.
+func (b *Builder[T]) Count(ctx context.Context) (int64, error) {
+	q, args, err := b.compile(ctx, "COUNT(*)", false)
+	if err != nil {
+		return 0, err
+	}
+	b.repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "Builder.Count"), slog.String("q", q))
+	var count int64
+	if err := b.repo.db.GetContext(ctx, &count, q, args...); err != nil {
+		return 0, fmt.Errorf("cannot execute query: %w", err)
+	}
+	return count, nil
+}
+
+// Iter streams the results of the query one row at a time.
+func (b *Builder[T]) Iter(ctx context.Context) Seq[T] {
+	q, args, err := b.compile(ctx, b.selectCols(), true)
+	if err != nil {
+		return func(yield func(*T, error) bool) {
+			yield(nil, err)
+		}
+	}
+	return newSeq[T](ctx, b.repo.db, b.repo.cnf, "Builder.Iter", q, args)
+}
@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func initBuilderRepo(t *testing.T) *RepoGeneric[testModel] {
+	ctx := context.Background()
+	db := connectDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "bar-name", Value: "bar-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "baz-name"}))
+
+	return repo
+}
+
+func TestBuilderFilter(t *testing.T) {
+	ctx := context.Background()
+	repo := initBuilderRepo(t)
+
+	results, err := repo.Select().Filter("Name", "=", "foo-name").Fetch(ctx)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, results[0].Value, "foo-value")
+
+	results, err = repo.Select().Filter("Value", "like", "ba%").Fetch(ctx)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, results[0].Name, "bar-name")
+
+	results, err = repo.Select().Filter("Name", ">", "bar-name").Order("Name").Fetch(ctx)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, results[0].Name, "baz-name")
+	require.Equal(t, results[1].Name, "foo-name")
+}
+
+func TestBuilderFilterIn(t *testing.T) {
+	ctx := context.Background()
+	repo := initBuilderRepo(t)
+
+	results, err := repo.Select().Filter("Name", "IN", []string{"foo-name", "baz-name"}).Order("-Name").Fetch(ctx)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, results[0].Name, "foo-name")
+	require.Equal(t, results[1].Name, "baz-name")
+}
+
+func TestBuilderFilterNull(t *testing.T) {
+	ctx := context.Background()
+	repo := initBuilderRepo(t)
+	_, err := repo.Exec(ctx, "UPDATE TestModels SET Value = NULL WHERE Name = ?", "baz-name")
+	require.NoError(t, err)
+
+	n, err := repo.Select().FilterNull("Value").Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+
+	n, err = repo.Select().FilterNotNull("Value").Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 2)
+}
+
+func TestBuilderLimitOffset(t *testing.T) {
+	ctx := context.Background()
+	repo := initBuilderRepo(t)
+
+	results, err := repo.Select().Order("Name").Limit(1).Offset(1).Fetch(ctx)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, results[0].Name, "baz-name")
+
+	results, err = repo.Select().Order("Name").Offset(2).Fetch(ctx)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, results[0].Name, "foo-name")
+}
+
+func TestBuilderFirst(t *testing.T) {
+	ctx := context.Background()
+	repo := initBuilderRepo(t)
+
+	model, err := repo.Select().Order("-Name").First(ctx)
+	require.NoError(t, err)
+	require.Equal(t, model.Name, "foo-name")
+
+	model, err = repo.Select().Filter("Name", "=", "qux-name").First(ctx)
+	require.Nil(t, model)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestBuilderUnknownColumn(t *testing.T) {
+	ctx := context.Background()
+	repo := initBuilderRepo(t)
+
+	_, err := repo.Select().Filter("Nmae", "=", "foo-name").Fetch(ctx)
+	require.EqualError(t, err, `unknown column "Nmae" in table TestModels`)
+
+	_, err = repo.Select().Order("-Vaule").Fetch(ctx)
+	require.EqualError(t, err, `unknown column "Vaule" in table TestModels`)
+
+	_, err = repo.Select().Filter("Name", "~", "foo-name").Fetch(ctx)
+	require.EqualError(t, err, `unknown operator "~"`)
+}
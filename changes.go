@@ -0,0 +1,137 @@
+package sqlite
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ChangeOp is the kind of write reported in a ChangeEvent.
+type ChangeOp string
+
+const (
+	ChangePut    ChangeOp = "put"
+	ChangeDelete ChangeOp = "delete"
+)
+
+// ChangeEvent describes a row written through a repository once its transaction has been committed.
+type ChangeEvent struct {
+	Table string
+	Key   any
+	Op    ChangeOp
+}
+
+type SubscribeOption func(opts *subscribeOptions)
+
+type subscribeOptions struct {
+	buffer int
+	tables map[string]bool
+}
+
+// WithChangeBuffer configures the number of events that can be queued in the subscription before
+// new ones are dropped. By default it is 100.
+func WithChangeBuffer(size int) SubscribeOption {
+	return func(opts *subscribeOptions) {
+		opts.buffer = size
+	}
+}
+
+// WithChangeTables receives only the events of the tables. By default all tables are received.
+func WithChangeTables(tables ...string) SubscribeOption {
+	return func(opts *subscribeOptions) {
+		for _, table := range tables {
+			opts.tables[table] = true
+		}
+	}
+}
+
+// Subscription receives the changes committed in a database.
+type Subscription struct {
+	// C receives the events in commit order. It is closed when the subscription is closed.
+	C <-chan ChangeEvent
+
+	db      *sqlx.DB
+	ch      chan ChangeEvent
+	tables  map[string]bool
+	dropped atomic.Int64
+}
+
+var (
+	subscriptionsMu sync.RWMutex
+	subscriptions   = make(map[*sqlx.DB]map[*Subscription]bool)
+)
+
+// Subscribe starts receiving the changes committed through the repositories of the database.
+// Events are never blocking the writers: if the subscriber does not keep up and the buffer is
+// full the new events are dropped and counted in Dropped. Rows modified with Exec, raw SQL or
+// foreign key actions are not reported.
+func Subscribe(db *sqlx.DB, options ...SubscribeOption) *Subscription {
+	opts := subscribeOptions{
+		buffer: 100,
+		tables: make(map[string]bool),
+	}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	ch := make(chan ChangeEvent, opts.buffer)
+	sub := &Subscription{
+		C:      ch,
+		db:     db,
+		ch:     ch,
+		tables: opts.tables,
+	}
+
+	subscriptionsMu.Lock()
+	defer subscriptionsMu.Unlock()
+	if subscriptions[db] == nil {
+		subscriptions[db] = make(map[*Subscription]bool)
+	}
+	subscriptions[db][sub] = true
+
+	return sub
+}
+
+// Dropped returns the number of events discarded because the buffer was full.
+func (sub *Subscription) Dropped() int64 {
+	return sub.dropped.Load()
+}
+
+// Close stops the subscription and closes its channel. It is safe to call it more than once.
+func (sub *Subscription) Close() {
+	subscriptionsMu.Lock()
+	defer subscriptionsMu.Unlock()
+
+	if !subscriptions[sub.db][sub] {
+		return
+	}
+	delete(subscriptions[sub.db], sub)
+	if len(subscriptions[sub.db]) == 0 {
+		delete(subscriptions, sub.db)
+	}
+	close(sub.ch)
+}
+
+// publishChanges delivers the events to the subscriptions of the database without blocking.
+func publishChanges(db *sqlx.DB, events []ChangeEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	subscriptionsMu.RLock()
+	defer subscriptionsMu.RUnlock()
+
+	for sub := range subscriptions[db] {
+		for _, event := range events {
+			if len(sub.tables) > 0 && !sub.tables[event.Table] {
+				continue
+			}
+			select {
+			case sub.ch <- event:
+			default:
+				sub.dropped.Add(1)
+			}
+		}
+	}
+}
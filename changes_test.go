@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribe(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	sub := Subscribe(db)
+	defer sub.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "bar-name", Value: "bar-value"}))
+	n, err := repo.DeleteWhere(ctx, "Value = ?", "bar-value")
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+
+	require.Equal(t, <-sub.C, ChangeEvent{Table: "TestModels", Key: "foo-name", Op: ChangePut})
+	require.Equal(t, <-sub.C, ChangeEvent{Table: "TestModels", Key: "bar-name", Op: ChangePut})
+	require.Equal(t, <-sub.C, ChangeEvent{Table: "TestModels", Key: "bar-name", Op: ChangeDelete})
+	require.Empty(t, sub.C)
+}
+
+func TestSubscribeRollback(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	sub := Subscribe(db)
+	defer sub.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	err = repo.RunInTx(ctx, func(tx *Tx[testModel]) error {
+		require.NoError(t, tx.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+		require.Empty(t, sub.C)
+		return errNotAllowed
+	})
+	require.ErrorIs(t, err, errNotAllowed)
+	require.Empty(t, sub.C)
+}
+
+func TestSubscribeTables(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	sub := Subscribe(db, WithChangeTables("TestVersioned"))
+	defer sub.Close()
+
+	models, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+	versioned, err := NewRepoGeneric(db, RepoConfig[testVersioned]{
+		Table:      "TestVersioned",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, models.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, versioned.Put(ctx, &testVersioned{Name: "bar-name", Value: "bar-value"}))
+
+	require.Equal(t, <-sub.C, ChangeEvent{Table: "TestVersioned", Key: "bar-name", Op: ChangePut})
+	require.Empty(t, sub.C)
+}
+
+func TestSubscribeDropped(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	sub := Subscribe(db, WithChangeBuffer(1))
+	defer sub.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.PutMulti(ctx, []*testModel{
+		{Name: "foo-name", Value: "foo-value"},
+		{Name: "bar-name", Value: "bar-value"},
+		{Name: "baz-name", Value: "baz-value"},
+	}))
+
+	require.Equal(t, <-sub.C, ChangeEvent{Table: "TestModels", Key: "foo-name", Op: ChangePut})
+	require.EqualValues(t, sub.Dropped(), 2)
+}
+
+func TestSubscribeClose(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	sub := Subscribe(db)
+	sub.Close()
+	sub.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+
+	_, ok := <-sub.C
+	require.False(t, ok)
+}
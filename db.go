@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
 )
 
 type OpenOption func(opts *openOptions)
@@ -61,7 +62,10 @@ func Open(dsn string, options ...OpenOption) (*sqlx.DB, error) {
 		db.SetMaxOpenConns(1)
 	}
 
-	db.MapperFunc(func(s string) string { return s })
+	// Columns are named after the fields unless there is a sqlite struct tag with the column
+	// name and its options. The db tags of sqlx are not read, and repositories reject the models
+	// that still rename columns with them.
+	db.Mapper = reflectx.NewMapperFunc("sqlite", func(s string) string { return s })
 
 	return db, nil
 }
@@ -4,6 +4,7 @@ import (
 	"log/slog"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
@@ -15,6 +16,60 @@ type testModel struct {
 	Value string
 }
 
+type testVersioned struct {
+	Name    string
+	Value   string
+	Version int64
+}
+
+type testTimestamps struct {
+	Name      string
+	Value     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type testSoftDeleted struct {
+	Name      string
+	Value     string
+	DeletedAt *time.Time
+}
+
+type testTagged struct {
+	Code        string `sqlite:"code,pk"`
+	DisplayName string `sqlite:"display_name"`
+	Status      string `sqlite:"status,omitempty"`
+	Revision    int64  `sqlite:"revision,readonly"`
+	Ignored     string `sqlite:"-"`
+}
+
+type testInteger struct {
+	ID    int64
+	Value string
+}
+
+type testComposite struct {
+	TenantID string `sqlite:"TenantID,pk"`
+	ID       int64  `sqlite:"ID,pk"`
+	Value    string
+}
+
+type testMember struct {
+	GroupID string `sqlite:"GroupID,pk"`
+	UserID  string `sqlite:"UserID,pk"`
+}
+
+type testTenant struct {
+	ID       string
+	TenantID string
+	Value    string
+}
+
+type testChild struct {
+	ID     string
+	Parent string
+}
+
 func connectDB(t *testing.T) *sqlx.DB {
 	slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
 
@@ -25,7 +80,67 @@ func connectDB(t *testing.T) *sqlx.DB {
 		CREATE TABLE IF NOT EXISTS TestModels (
 			Name TEXT NOT NULL PRIMARY KEY,
 			Value TEXT
-		)
+		);
+
+		CREATE TABLE IF NOT EXISTS TestVersioned (
+			Name TEXT NOT NULL PRIMARY KEY,
+			Value TEXT,
+			Version INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS TestTimestamps (
+			Name TEXT NOT NULL PRIMARY KEY,
+			Value TEXT,
+			CreatedAt DATETIME NOT NULL,
+			UpdatedAt DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS TestSoftDeleted (
+			Name TEXT NOT NULL PRIMARY KEY,
+			Value TEXT,
+			DeletedAt DATETIME
+		);
+
+		CREATE TABLE IF NOT EXISTS test_tagged (
+			code TEXT NOT NULL PRIMARY KEY,
+			display_name TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'active',
+			revision INTEGER NOT NULL DEFAULT 7
+		);
+
+		CREATE TABLE IF NOT EXISTS TestIntegers (
+			ID INTEGER PRIMARY KEY,
+			Value TEXT
+		);
+
+		CREATE TABLE IF NOT EXISTS TestAutoIncrement (
+			ID INTEGER PRIMARY KEY AUTOINCREMENT,
+			Value TEXT
+		);
+
+		CREATE TABLE IF NOT EXISTS TestComposite (
+			TenantID TEXT NOT NULL,
+			ID INTEGER NOT NULL,
+			Value TEXT,
+			PRIMARY KEY (TenantID, ID)
+		);
+
+		CREATE TABLE IF NOT EXISTS TestMembers (
+			GroupID TEXT NOT NULL,
+			UserID TEXT NOT NULL,
+			PRIMARY KEY (GroupID, UserID)
+		);
+
+		CREATE TABLE IF NOT EXISTS TestTenants (
+			ID TEXT NOT NULL PRIMARY KEY,
+			TenantID TEXT NOT NULL,
+			Value TEXT
+		);
+
+		CREATE TABLE IF NOT EXISTS TestChildren (
+			ID TEXT NOT NULL PRIMARY KEY,
+			Parent TEXT NOT NULL REFERENCES TestModels(Name) ON DELETE CASCADE
+		);
 	`)
 	require.NoError(t, err)
 
@@ -3,15 +3,26 @@ package sqlite
 import (
 	"context"
 	"fmt"
+	"log/slog"
 )
 
 type Hooks[T any] struct {
-	BeforePut []Hook[T]
-	AfterPut  []Hook[T]
+	BeforePut    []Hook[T]
+	AfterPut     []Hook[T]
+	BeforeDelete []TxHook[T]
+	AfterDelete  []TxHook[T]
+	AfterLoad    []Hook[T]
+
+	// AfterCommit hooks run once the transaction that stored the model has been committed.
+	// Errors cannot undo the changes anymore, so they are only logged.
+	AfterCommit []Hook[T]
 }
 
 type Hook[T any] func(ctx context.Context, model *T) error
 
+// TxHook receives the transaction where the operation runs, so it can modify other rows atomically.
+type TxHook[T any] func(ctx context.Context, tx *Tx[T], model *T) error
+
 type HookBeforePut[T any] interface {
 	BeforePut(ctx context.Context, tx *Tx[T]) error
 }
@@ -49,3 +60,84 @@ func runAfterPut[T any](ctx context.Context, hooks Hooks[T], model *T) error {
 	}
 	return nil
 }
+
+type HookBeforeDelete[T any] interface {
+	BeforeDelete(ctx context.Context, tx *Tx[T]) error
+}
+
+func runBeforeDelete[T any](ctx context.Context, tx *Tx[T], hooks Hooks[T], model *T) error {
+	if hook, ok := any(model).(HookBeforeDelete[T]); ok {
+		if err := hook.BeforeDelete(ctx, tx); err != nil {
+			return fmt.Errorf("before delete hook: %w", err)
+		}
+	}
+	for _, hook := range hooks.BeforeDelete {
+		if err := hook(ctx, tx, model); err != nil {
+			return fmt.Errorf("global before delete hook: %w", err)
+		}
+	}
+	return nil
+}
+
+type HookAfterDelete[T any] interface {
+	AfterDelete(ctx context.Context, tx *Tx[T]) error
+}
+
+func runAfterDelete[T any](ctx context.Context, tx *Tx[T], hooks Hooks[T], model *T) error {
+	if hook, ok := any(model).(HookAfterDelete[T]); ok {
+		if err := hook.AfterDelete(ctx, tx); err != nil {
+			return fmt.Errorf("after delete hook: %w", err)
+		}
+	}
+	for _, hook := range hooks.AfterDelete {
+		if err := hook(ctx, tx, model); err != nil {
+			return fmt.Errorf("global after delete hook: %w", err)
+		}
+	}
+	return nil
+}
+
+type HookAfterLoad interface {
+	AfterLoad(ctx context.Context) error
+}
+
+func runAfterLoad[T any](ctx context.Context, hooks Hooks[T], models ...*T) error {
+	for _, model := range models {
+		if model == nil {
+			continue
+		}
+		if hook, ok := any(model).(HookAfterLoad); ok {
+			if err := hook.AfterLoad(ctx); err != nil {
+				return fmt.Errorf("after load hook: %w", err)
+			}
+		}
+		for _, hook := range hooks.AfterLoad {
+			if err := hook(ctx, model); err != nil {
+				return fmt.Errorf("global after load hook: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+type HookAfterCommit interface {
+	AfterCommit(ctx context.Context) error
+}
+
+func registerAfterCommit[T any](ctx context.Context, tx *Tx[T], hooks Hooks[T], model *T) {
+	if hook, ok := any(model).(HookAfterCommit); ok {
+		tx.AfterCommit(func() {
+			if err := hook.AfterCommit(ctx); err != nil {
+				tx.cnf.Logger.ErrorContext(ctx, "After commit hook failed", slog.String("error", err.Error()))
+			}
+		})
+	}
+	for _, hook := range hooks.AfterCommit {
+		hook := hook
+		tx.AfterCommit(func() {
+			if err := hook(ctx, model); err != nil {
+				tx.cnf.Logger.ErrorContext(ctx, "Global after commit hook failed", slog.String("error", err.Error()))
+			}
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errNotAllowed = errors.New("not allowed")
+
+type testDeleteHooked struct {
+	Name  string
+	Value string
+}
+
+func (model *testDeleteHooked) BeforeDelete(ctx context.Context, tx *Tx[testDeleteHooked]) error {
+	_, err := tx.Exec(ctx, "DELETE FROM TestChildren WHERE Parent = ?", model.Name)
+	return err
+}
+
+func TestHooksBeforeDeleteModel(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testDeleteHooked]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+	children, err := NewRepoGeneric(db, RepoConfig[testChild]{
+		Table:      "TestChildren",
+		PrimaryKey: "ID",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testDeleteHooked{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, repo.Put(ctx, &testDeleteHooked{Name: "bar-name", Value: "bar-value"}))
+	require.NoError(t, children.Put(ctx, &testChild{ID: "foo-child", Parent: "foo-name"}))
+	require.NoError(t, children.Put(ctx, &testChild{ID: "bar-child", Parent: "bar-name"}))
+
+	require.NoError(t, repo.DeleteKey(ctx, "foo-name"))
+
+	n, err := children.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+
+	n, err = repo.DeleteWhere(ctx, "Value = ?", "bar-value")
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+
+	n, err = children.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 0)
+}
+
+func TestHooksDeleteGlobal(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	var before, after []string
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+		Hooks: Hooks[testModel]{
+			BeforeDelete: []TxHook[testModel]{
+				func(ctx context.Context, tx *Tx[testModel], model *testModel) error {
+					exists, err := tx.Exists(ctx, model.Name)
+					require.NoError(t, err)
+					require.True(t, exists)
+					before = append(before, model.Name)
+					return nil
+				},
+			},
+			AfterDelete: []TxHook[testModel]{
+				func(ctx context.Context, tx *Tx[testModel], model *testModel) error {
+					exists, err := tx.Exists(ctx, model.Name)
+					require.NoError(t, err)
+					require.False(t, exists)
+					after = append(after, model.Name)
+					return nil
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "bar-name", Value: "bar-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "baz-name", Value: "baz-value"}))
+
+	require.NoError(t, repo.Delete(ctx, &testModel{Name: "foo-name"}))
+	n, err := repo.DeleteMulti(ctx, []string{"bar-name", "baz-name", "qux-name"})
+	require.NoError(t, err)
+	require.EqualValues(t, n, 2)
+	require.NoError(t, repo.DeleteKey(ctx, "qux-name"))
+	require.NoError(t, repo.Delete(ctx, &testModel{Name: "qux-name"}))
+	require.NoError(t, repo.Delete(ctx, &testModel{Name: "foo-name"}))
+
+	require.Equal(t, before, []string{"foo-name", "bar-name", "baz-name"})
+	require.Equal(t, after, before)
+}
+
+func TestHooksDeleteSoftDeleted(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	var before, after []string
+	repo, err := NewRepoGeneric(db, RepoConfig[testSoftDeleted]{
+		Table:      "TestSoftDeleted",
+		PrimaryKey: "Name",
+		SoftDelete: "DeletedAt",
+		Hooks: Hooks[testSoftDeleted]{
+			BeforeDelete: []TxHook[testSoftDeleted]{
+				func(ctx context.Context, tx *Tx[testSoftDeleted], model *testSoftDeleted) error {
+					before = append(before, model.Name)
+					return nil
+				},
+			},
+			AfterDelete: []TxHook[testSoftDeleted]{
+				func(ctx context.Context, tx *Tx[testSoftDeleted], model *testSoftDeleted) error {
+					after = append(after, model.Name)
+					return nil
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testSoftDeleted{Name: "foo-name", Value: "foo-value"}))
+
+	require.NoError(t, repo.Delete(ctx, &testSoftDeleted{Name: "foo-name"}))
+	require.NoError(t, repo.Delete(ctx, &testSoftDeleted{Name: "foo-name"}))
+	require.NoError(t, repo.WithDeleted().Delete(ctx, &testSoftDeleted{Name: "foo-name"}))
+	require.NoError(t, repo.DeleteKey(ctx, "foo-name"))
+
+	require.Equal(t, before, []string{"foo-name"})
+	require.Equal(t, after, before)
+}
+
+func TestHooksDeleteErrorRollback(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+		Hooks: Hooks[testModel]{
+			AfterDelete: []TxHook[testModel]{
+				func(ctx context.Context, tx *Tx[testModel], model *testModel) error {
+					return errNotAllowed
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+
+	err = repo.DeleteKey(ctx, "foo-name")
+	require.ErrorIs(t, err, errNotAllowed)
+	require.EqualError(t, err, "global after delete hook: not allowed")
+
+	n, err := repo.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+}
+
+type testLoaded struct {
+	Name  string
+	Value string
+}
+
+func (model *testLoaded) AfterLoad(ctx context.Context) error {
+	model.Value = strings.ToUpper(model.Value)
+	return nil
+}
+
+func TestHooksAfterLoadModel(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testLoaded]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testLoaded{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, repo.Put(ctx, &testLoaded{Name: "bar-name", Value: "bar-value"}))
+
+	model, err := repo.Get(ctx, "foo-name")
+	require.NoError(t, err)
+	require.Equal(t, model.Value, "FOO-VALUE")
+
+	models, err := repo.GetMulti(ctx, []string{"foo-name", "bar-name"})
+	require.NoError(t, err)
+	require.Equal(t, models[0].Value, "FOO-VALUE")
+	require.Equal(t, models[1].Value, "BAR-VALUE")
+
+	models, err = repo.QueryList(ctx, "SELECT * FROM TestModels WHERE Name = ?", "bar-name")
+	require.NoError(t, err)
+	require.Equal(t, models[0].Value, "BAR-VALUE")
+
+	repo.ListIter(ctx)(func(model *testLoaded, err error) bool {
+		require.NoError(t, err)
+		require.Equal(t, model.Value, strings.ToUpper(model.Value))
+		return true
+	})
+
+	singleton, err := NewRepoSingleton(db, RepoConfig[testLoaded]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+	model, err = singleton.Get(ctx, "bar-name")
+	require.NoError(t, err)
+	require.Equal(t, model.Value, "BAR-VALUE")
+}
+
+func TestHooksAfterLoadGlobal(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	var loaded []string
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+		Hooks: Hooks[testModel]{
+			AfterLoad: []Hook[testModel]{
+				func(ctx context.Context, model *testModel) error {
+					loaded = append(loaded, model.Name)
+					return nil
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "bar-name", Value: "bar-value"}))
+
+	_, err = repo.List(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, loaded, []string{"foo-name", "bar-name"})
+
+	loaded = nil
+	_, err = repo.Select().Filter("Name", "=", "foo-name").Fetch(ctx)
+	require.NoError(t, err)
+	require.Equal(t, loaded, []string{"foo-name"})
+
+	loaded = nil
+	_, _, err = repo.ListPage(ctx, "", 1)
+	require.NoError(t, err)
+	require.Equal(t, loaded, []string{"bar-name"})
+}
+
+func TestHooksAfterLoadError(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+		Hooks: Hooks[testModel]{
+			AfterLoad: []Hook[testModel]{
+				func(ctx context.Context, model *testModel) error {
+					return errNotAllowed
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+
+	model, err := repo.Get(ctx, "foo-name")
+	require.Nil(t, model)
+	require.EqualError(t, err, "global after load hook: not allowed")
+}
+
+func TestHooksAfterCommit(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	var committed []string
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+		Hooks: Hooks[testModel]{
+			AfterCommit: []Hook[testModel]{
+				func(ctx context.Context, model *testModel) error {
+					committed = append(committed, model.Name)
+					return nil
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	tx, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	require.NoError(t, tx.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+	tx.AfterCommit(func() {
+		committed = append(committed, "caller")
+	})
+	require.Empty(t, committed)
+
+	require.NoError(t, tx.Commit())
+	require.Equal(t, committed, []string{"foo-name", "caller"})
+}
+
+func TestHooksAfterCommitRollback(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	var committed []string
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+		Hooks: Hooks[testModel]{
+			AfterCommit: []Hook[testModel]{
+				func(ctx context.Context, model *testModel) error {
+					committed = append(committed, model.Name)
+					return nil
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	err = repo.RunInTx(ctx, func(tx *Tx[testModel]) error {
+		require.NoError(t, tx.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+		return errNotAllowed
+	})
+	require.ErrorIs(t, err, errNotAllowed)
+	require.Empty(t, committed)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "bar-name", Value: "bar-value"}))
+	require.Equal(t, committed, []string{"bar-name"})
+}
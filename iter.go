@@ -0,0 +1,45 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Seq is a streaming iterator over query results. It has the same shape as iter.Seq2[*T, error]
+// so it can be used directly in range loops. The iteration stops after the first error is yielded.
+// The underlying cursor is closed when the iteration finishes, when the consumer stops early or
+// when the context is cancelled.
+type Seq[T any] func(yield func(*T, error) bool)
+
+func newSeq[T any](ctx context.Context, db *sqlx.DB, cnf RepoConfig[T], method, query string, args []any) Seq[T] {
+	return func(yield func(*T, error) bool) {
+		cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", method), slog.String("q", query))
+		rows, err := db.QueryxContext(ctx, query, args...)
+		if err != nil {
+			yield(nil, fmt.Errorf("cannot execute query: %w", err))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			model := new(T)
+			if err := rows.StructScan(model); err != nil {
+				yield(nil, fmt.Errorf("cannot scan row: %w", err))
+				return
+			}
+			if err := runAfterLoad(ctx, cnf.Hooks, model); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(model, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(nil, fmt.Errorf("cannot read rows: %w", err))
+		}
+	}
+}
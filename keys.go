@@ -0,0 +1,210 @@
+package sqlite
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Key is the value of a composite primary key, with one element for each column in the order of
+// RepoConfig.PrimaryKeys. Repositories with a single primary key column receive the value
+// directly instead.
+type Key []any
+
+func (k Key) String() string {
+	parts := make([]string, len(k))
+	for i, v := range k {
+		parts[i] = formatKey(v)
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+func (s store[T]) composite() bool {
+	return len(s.cnf.PrimaryKeys) > 1
+}
+
+// pkFields returns the primary key fields of the model in the order of the columns.
+func (s store[T]) pkFields(model *T) []reflect.Value {
+	v := reflect.ValueOf(model)
+	fields := make([]reflect.Value, len(s.cnf.PrimaryKeys))
+	for i, col := range s.cnf.PrimaryKeys {
+		fields[i] = s.db.Mapper.FieldByName(v, col)
+	}
+	return fields
+}
+
+// getPK returns the field of the primary key for the repositories with a single column.
+func (s store[T]) getPK(model *T) reflect.Value {
+	return s.pkFields(model)[0]
+}
+
+// modelKey returns the key of the model, a Key for composite primary keys or the value of the
+// column otherwise.
+func (s store[T]) modelKey(model *T) any {
+	fields := s.pkFields(model)
+	if !s.composite() {
+		return fields[0].Interface()
+	}
+	key := make(Key, len(fields))
+	for i, field := range fields {
+		key[i] = field.Interface()
+	}
+	return key
+}
+
+// setKey stores the key in the primary key fields of the model. The key must have been
+// converted with keyValue before.
+func (s store[T]) setKey(model *T, key any) {
+	for i, field := range s.pkFields(model) {
+		field.Set(reflect.ValueOf(s.keyArgs(key)[i]))
+	}
+}
+
+// keyArgs returns the arguments for the columns of the key. The key must have been converted
+// with keyValue before.
+func (s store[T]) keyArgs(key any) []any {
+	if s.composite() {
+		return key.(Key)
+	}
+	return []any{key}
+}
+
+// mapKey returns a comparable version of the key to index the models in a map. Composite keys
+// are indexed by their text representation.
+func (s store[T]) mapKey(key any) any {
+	if s.composite() {
+		return key.(Key).String()
+	}
+	return key
+}
+
+// pkWhere returns the condition that matches a single key.
+func (s store[T]) pkWhere() string {
+	conds := make([]string, len(s.cnf.PrimaryKeys))
+	for i, col := range s.cnf.PrimaryKeys {
+		conds[i] = col + " = ?"
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// pkIn returns the condition and arguments that match any of the keys, using row values for
+// composite keys. The keys must have been converted with keyValues before.
+func (s store[T]) pkIn(keys []any) (string, []any) {
+	if !s.composite() {
+		return fmt.Sprintf("%s IN (%s)", s.cnf.PrimaryKeys[0], placeholders(len(keys))), keys
+	}
+
+	rows := make([]string, len(keys))
+	var args []any
+	for i, key := range keys {
+		rows[i] = "(" + placeholders(len(s.cnf.PrimaryKeys)) + ")"
+		args = append(args, key.(Key)...)
+	}
+	return fmt.Sprintf("(%s) IN (%s)", strings.Join(s.cnf.PrimaryKeys, ","), strings.Join(rows, ",")), args
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+func (s store[T]) pkTypes() []reflect.Type {
+	tm := s.db.Mapper.TypeMap(reflect.TypeOf((*T)(nil)).Elem())
+	types := make([]reflect.Type, len(s.cnf.PrimaryKeys))
+	for i, col := range s.cnf.PrimaryKeys {
+		types[i] = tm.GetByPath(col).Field.Type
+	}
+	return types
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// convertValue converts a value to the type of a column, so any integer can be used for an
+// integer column or a plain string for a column with a named string type.
+func convertValue(t reflect.Type, value any) (any, bool) {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return nil, false
+	}
+	if v.Type() == t {
+		return value, true
+	}
+	if v.Type().ConvertibleTo(t) && (v.Kind() == t.Kind() || isIntKind(v.Kind()) && isIntKind(t.Kind())) {
+		return v.Convert(t).Interface(), true
+	}
+	return nil, false
+}
+
+// convertKey converts a value to the type of a primary key column.
+func convertKey(col string, t reflect.Type, value any) (any, error) {
+	if value == nil {
+		return nil, fmt.Errorf("invalid nil key for primary key %s", col)
+	}
+	converted, ok := convertValue(t, value)
+	if !ok {
+		return nil, fmt.Errorf("invalid key type %T for primary key %s of type %s", value, col, t)
+	}
+	return converted, nil
+}
+
+// keyValue converts the key to the types of the primary key fields. Composite keys must be a Key
+// with one value for each column.
+func (s store[T]) keyValue(key any) (any, error) {
+	types := s.pkTypes()
+	if !s.composite() {
+		return convertKey(s.cnf.PrimaryKeys[0], types[0], key)
+	}
+
+	values, ok := key.(Key)
+	if !ok {
+		return nil, fmt.Errorf("invalid key type %T for composite primary key of table %s", key, s.cnf.Table)
+	}
+	if len(values) != len(types) {
+		return nil, fmt.Errorf("invalid key %s with %d values for composite primary key of table %s", values, len(values), s.cnf.Table)
+	}
+	converted := make(Key, len(values))
+	for i, value := range values {
+		v, err := convertKey(s.cnf.PrimaryKeys[i], types[i], value)
+		if err != nil {
+			return nil, err
+		}
+		converted[i] = v
+	}
+	return converted, nil
+}
+
+// keyValues converts a slice of keys to the types of the primary key fields.
+func (s store[T]) keyValues(keys any) ([]any, error) {
+	if keys == nil {
+		return nil, nil
+	}
+	v := reflect.ValueOf(keys)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("keys must be a slice, not %s", v.Type())
+	}
+	values := make([]any, v.Len())
+	for i := range values {
+		key, err := s.keyValue(v.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		values[i] = key
+	}
+	return values, nil
+}
+
+// isEmptyKey reports if any of the values of the key is empty.
+func (s store[T]) isEmptyKey(key any) bool {
+	for _, arg := range s.keyArgs(key) {
+		if reflect.ValueOf(arg).IsZero() {
+			return true
+		}
+	}
+	return false
+}
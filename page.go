@@ -0,0 +1,101 @@
+package sqlite
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrInvalidPageToken is returned when a page token cannot be decoded.
+var ErrInvalidPageToken = errors.New("sqlite: invalid page token")
+
+// Same layout used by go-sqlite3 to store time values, so the cursor compares correctly with the column.
+const pageTimeLayout = "2006-01-02 15:04:05.999999999-07:00"
+
+func encodePageToken(cursor []any) (string, error) {
+	values := make([]any, len(cursor))
+	for i, value := range cursor {
+		value, err := pageValue(value)
+		if err != nil {
+			return "", err
+		}
+		if t, ok := value.(time.Time); ok {
+			value = t.Format(pageTimeLayout)
+		}
+		values[i] = value
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("cannot encode page token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodePageToken(token string) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPageToken, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var cursor []any
+	if err := decoder.Decode(&cursor); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPageToken, err)
+	}
+	for i, value := range cursor {
+		n, ok := value.(json.Number)
+		if !ok {
+			continue
+		}
+		if v, err := n.Int64(); err == nil {
+			cursor[i] = v
+		} else if v, err := n.Float64(); err == nil {
+			cursor[i] = v
+		} else {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidPageToken, err)
+		}
+	}
+	return cursor, nil
+}
+
+// pageValue returns the value of a column as it is stored, dereferencing pointers and using the
+// value of the types that implement driver.Valuer.
+func pageValue(value any) (any, error) {
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, nil
+		}
+		value = v.Elem().Interface()
+	}
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode page token: %w", err)
+		}
+		return v, nil
+	}
+	return value, nil
+}
+
+// pageAfter returns the condition that matches the rows sorted after the cursor. When the order
+// has an extra column before the primary key it can be NULL, which SQLite sorts before any other
+// value and never matches in a row value comparison.
+func pageAfter(order []string, keys []string, cursor []any) (string, []any) {
+	after := fmt.Sprintf("(%s) > (%s)", strings.Join(keys, ","), placeholders(len(keys)))
+	if len(order) == len(keys) {
+		return after, cursor
+	}
+
+	col := order[0]
+	if cursor[0] == nil {
+		return fmt.Sprintf("(%s IS NULL AND %s OR %s IS NOT NULL)", col, after, col), cursor[1:]
+	}
+	args := append([]any{cursor[0], cursor[0]}, cursor[1:]...)
+	return fmt.Sprintf("(%s > ? OR %s = ? AND %s)", col, col, after), args
+}
@@ -18,6 +18,11 @@ type Query[T any] struct {
 	sql     string
 	pending map[string]bool
 	args    []any
+
+	// order lists the names of the arguments in the position of their placeholders, so they can
+	// be bound in any order. If empty the arguments are used in the order they are bound.
+	order []string
+	named map[string]any
 }
 
 func NewQuery[T any](repo queryable, sql string, pending []string) *Query[T] {
@@ -25,6 +30,7 @@ func NewQuery[T any](repo queryable, sql string, pending []string) *Query[T] {
 		db:      repo.conn(),
 		sql:     sql,
 		pending: make(map[string]bool),
+		named:   make(map[string]any),
 	}
 	for _, name := range pending {
 		q.pending[name] = true
@@ -35,10 +41,23 @@ func NewQuery[T any](repo queryable, sql string, pending []string) *Query[T] {
 func (q *Query[T]) Bind(args ...sql.NamedArg) {
 	for _, arg := range args {
 		q.pending[arg.Name] = false
+		q.named[arg.Name] = arg.Value
 		q.args = append(q.args, arg.Value)
 	}
 }
 
+// queryArgs returns the arguments in the position of their placeholders.
+func (q *Query[T]) queryArgs() []any {
+	if len(q.order) == 0 {
+		return q.args
+	}
+	args := make([]any, len(q.order))
+	for i, name := range q.order {
+		args[i] = q.named[name]
+	}
+	return args
+}
+
 func (q *Query[T]) checkPending() error {
 	for name, pending := range q.pending {
 		if pending {
@@ -56,7 +75,7 @@ func (q *Query[T]) Query(ctx context.Context, args ...sql.NamedArg) (*T, error)
 
 	var model T
 	slog.Debug("SQL", slog.String("method", "Query.Query"), slog.String("q", q.sql))
-	if err := q.db.GetContext(ctx, &model, q.sql, q.args...); err != nil {
+	if err := q.db.GetContext(ctx, &model, q.sql, q.queryArgs()...); err != nil {
 		return nil, err
 	}
 	return &model, nil
@@ -71,7 +90,7 @@ func (q *Query[T]) QueryValue(ctx context.Context, args ...sql.NamedArg) (T, err
 	}
 
 	slog.Debug("SQL", slog.String("method", "Query.QueryValue"), slog.String("q", q.sql))
-	if err := q.db.GetContext(ctx, &model, q.sql, q.args...); err != nil {
+	if err := q.db.GetContext(ctx, &model, q.sql, q.queryArgs()...); err != nil {
 		return model, err
 	}
 	return model, nil
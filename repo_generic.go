@@ -3,53 +3,100 @@ package sqlite
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 	"log/slog"
 	"reflect"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
 )
 
 type RepoGeneric[T any] struct {
-	db  *sqlx.DB
-	cnf RepoConfig[T]
+	db          *sqlx.DB
+	cnf         RepoConfig[T]
+	withDeleted bool
+	tenant      any
 }
 
-func NewRepoGeneric[T any](db *sqlx.DB, cnf RepoConfig[T]) *RepoGeneric[T] {
-	cnf.fillDefaults()
+// NewRepoGeneric creates a repository for the models stored in a table. It returns an error if
+// the configuration does not match the fields of the model.
+func NewRepoGeneric[T any](db *sqlx.DB, cnf RepoConfig[T]) (*RepoGeneric[T], error) {
+	if err := cnf.prepare(db); err != nil {
+		return nil, fmt.Errorf("invalid repository config: %w", err)
+	}
 	return &RepoGeneric[T]{
 		db:  db,
 		cnf: cnf,
-	}
+	}, nil
 }
 
 func (repo *RepoGeneric[T]) conn() *sqlx.DB {
 	return repo.db
 }
 
+func (repo *RepoGeneric[T]) store() store[T] {
+	return store[T]{db: repo.db, ext: repo.db, cnf: repo.cnf, prefix: "RepoGeneric", withDeleted: repo.withDeleted, tenant: repo.tenant}
+}
+
+// WithDeleted returns a view of the repository whose reads include the soft deleted rows.
+func (repo *RepoGeneric[T]) WithDeleted() *RepoGeneric[T] {
+	clone := *repo
+	clone.withDeleted = true
+	return &clone
+}
+
+// ForTenant returns a view of the repository restricted to the rows of the tenant, ignoring the
+// tenant of the context.
+func (repo *RepoGeneric[T]) ForTenant(tenant any) *RepoGeneric[T] {
+	clone := *repo
+	clone.tenant = tenant
+	return &clone
+}
+
 func (repo *RepoGeneric[T]) Count(ctx context.Context) (int64, error) {
-	var count int64
-	q := fmt.Sprintf("SELECT COUNT(*) FROM %s", repo.cnf.Table)
-	repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoGeneric.Count"), slog.String("q", q))
-	if err := repo.db.GetContext(ctx, &count, q); err != nil {
-		return 0, fmt.Errorf("cannot execute query: %w", err)
-	}
-	return count, nil
+	return repo.store().count(ctx)
 }
 
 func (repo *RepoGeneric[T]) BeginTx(ctx context.Context) (*Tx[T], error) {
-	return newTx(ctx, repo.db, repo.cnf)
+	tx, err := newTx(ctx, repo.db, repo.cnf)
+	if err != nil {
+		return nil, err
+	}
+	tx.withDeleted = repo.withDeleted
+	tx.tenant = repo.tenant
+	return tx, nil
 }
 
-func (repo *RepoGeneric[T]) Put(ctx context.Context, model *T) error {
+// WithTx returns a view of the shared transaction to operate with the models of this repository.
+func (repo *RepoGeneric[T]) WithTx(tx *SharedTx) *Tx[T] {
+	return &Tx[T]{
+		shared:      tx,
+		cnf:         repo.cnf,
+		withDeleted: repo.withDeleted,
+		tenant:      repo.tenant,
+	}
+}
+
+// RunInTx runs fn inside a transaction that is committed if it returns nil and rolled back otherwise.
+// It is retried when the database is busy. See the package level RunInTx for details.
+func (repo *RepoGeneric[T]) RunInTx(ctx context.Context, fn func(tx *Tx[T]) error, options ...TxOption) error {
+	begin := func() (*Tx[T], error) {
+		return repo.BeginTx(ctx)
+	}
+	return runInTx(ctx, begin, fn, options)
+}
+
+// inTx runs fn inside a new transaction and commits it if there are no errors.
+func (repo *RepoGeneric[T]) inTx(ctx context.Context, fn func(tx *Tx[T]) error) error {
 	tx, err := repo.BeginTx(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
-	if err := tx.Put(ctx, model); err != nil {
+	if err := fn(tx); err != nil {
 		return err
 	}
 	if err := tx.Commit(); err != nil {
@@ -58,160 +105,270 @@ func (repo *RepoGeneric[T]) Put(ctx context.Context, model *T) error {
 	return nil
 }
 
-func (repo *RepoGeneric[T]) List(ctx context.Context) ([]*T, error) {
-	var models []*T
-	var single T
-	cols, _ := listCols(repo.db, single)
-	q := fmt.Sprintf("SELECT %s FROM %s", strings.Join(cols, ","), repo.cnf.Table)
-	repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoGeneric.List"), slog.String("q", q))
-	if err := repo.db.SelectContext(ctx, &models, q); err != nil {
-		return nil, fmt.Errorf("cannot execute query: %w", err)
-	}
-	return models, nil
+func (repo *RepoGeneric[T]) Put(ctx context.Context, model *T) error {
+	return repo.inTx(ctx, func(tx *Tx[T]) error {
+		return tx.Put(ctx, model)
+	})
 }
 
-func (repo *RepoGeneric[T]) Get(ctx context.Context, key string) (*T, error) {
-	if key == "" {
-		return nil, fmt.Errorf("empty key: %w", sql.ErrNoRows)
-	}
+// Insert stores a new model. It fails with an AlreadyExistsError if the key is already in use.
+func (repo *RepoGeneric[T]) Insert(ctx context.Context, model *T) error {
+	return repo.inTx(ctx, func(tx *Tx[T]) error {
+		return tx.Insert(ctx, model)
+	})
+}
 
-	var model T
-	cols, _ := listCols(repo.db, model)
-	q := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", strings.Join(cols, ","), repo.cnf.Table, repo.cnf.PrimaryKey)
-	repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoGeneric.Get"), slog.String("q", q), slog.String("key", key))
-	if err := repo.db.GetContext(ctx, &model, q, key); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("%w: %w", &MissingKeyError{key}, err)
-		}
-		return nil, fmt.Errorf("cannot execute query: %w", err)
+// Update modifies an existing model. It fails with a MissingKeyError if the key does not exist.
+func (repo *RepoGeneric[T]) Update(ctx context.Context, model *T) error {
+	return repo.inTx(ctx, func(tx *Tx[T]) error {
+		return tx.Update(ctx, model)
+	})
+}
+
+// UpdateFields modifies only the columns in fields for the row with the key. It fails with a
+// MissingKeyError if the key does not exist.
+func (repo *RepoGeneric[T]) UpdateFields(ctx context.Context, key any, fields map[string]any) error {
+	return repo.inTx(ctx, func(tx *Tx[T]) error {
+		return tx.UpdateFields(ctx, key, fields)
+	})
+}
+
+// Upsert inserts the model or updates the existing row in place if the key is already in use.
+func (repo *RepoGeneric[T]) Upsert(ctx context.Context, model *T) error {
+	return repo.inTx(ctx, func(tx *Tx[T]) error {
+		return tx.Upsert(ctx, model)
+	})
+}
+
+// PutMulti stores all the models in a single transaction. If any of them fails nothing is
+// stored and a joined error with a PutError for each failed model is returned.
+func (repo *RepoGeneric[T]) PutMulti(ctx context.Context, models []*T) error {
+	if len(models) == 0 {
+		return nil
 	}
-	return &model, nil
+	return repo.inTx(ctx, func(tx *Tx[T]) error {
+		return tx.PutMulti(ctx, models)
+	})
+}
+
+func (repo *RepoGeneric[T]) List(ctx context.Context) ([]*T, error) {
+	return repo.store().list(ctx)
 }
 
-func (repo *RepoGeneric[T]) GetMulti(ctx context.Context, keys []string) ([]*T, error) {
-	if len(keys) == 0 {
-		return nil, nil
+// ListPage returns a page of at most pageSize models and the token to request the next one.
+// The token is empty when there are no more results. Pass an empty token to get the first page.
+func (repo *RepoGeneric[T]) ListPage(ctx context.Context, pageToken string, pageSize int) ([]*T, string, error) {
+	if pageSize <= 0 {
+		return nil, "", fmt.Errorf("invalid page size: %d", pageSize)
 	}
 
-	var model T
-	cols, _ := listCols(repo.db, model)
-	q, args, err := sqlx.In(fmt.Sprintf("SELECT %s FROM %s WHERE %s IN (?)", strings.Join(cols, ","), repo.cnf.Table, repo.cnf.PrimaryKey), keys)
-	if err != nil {
-		return nil, fmt.Errorf("cannot prepare sql statement: %w", err)
+	var order []string
+	if repo.cnf.PageOrder != "" && !slices.Contains(repo.cnf.PrimaryKeys, repo.cnf.PageOrder) {
+		order = append(order, repo.cnf.PageOrder)
 	}
-	models, err := repo.QueryMap(ctx, q, args...)
+	order = append(order, repo.cnf.PrimaryKeys...)
+
+	var single T
+	cols, _ := listCols(repo.db, single)
+	var conds []string
+	var args []any
+	if pageToken != "" {
+		cursor, err := decodePageToken(pageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(cursor) != len(order) {
+			return nil, "", fmt.Errorf("%w: unexpected cursor length %d", ErrInvalidPageToken, len(cursor))
+		}
+		var cond string
+		cond, args = pageAfter(order, repo.cnf.PrimaryKeys, cursor)
+		conds = append(conds, cond)
+	}
+	where, args, err := repo.store().where(ctx, conds, args)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	q := fmt.Sprintf("SELECT %s FROM %s%s", strings.Join(cols, ","), repo.cnf.Table, where)
+	q += fmt.Sprintf(" ORDER BY %s LIMIT %d", strings.Join(order, ","), pageSize+1)
+	repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoGeneric.ListPage"), slog.String("q", q))
+
+	var models []*T
+	if err := repo.db.SelectContext(ctx, &models, q, args...); err != nil {
+		return nil, "", fmt.Errorf("cannot execute query: %w", err)
 	}
 
-	var multi []error
-	var results []*T
-	for _, key := range keys {
-		if models[key] == nil {
-			multi = append(multi, fmt.Errorf("%w: %w", &MissingKeyError{key}, sql.ErrNoRows))
-			results = append(results, nil)
-		} else {
-			results = append(results, models[key])
+	var token string
+	if len(models) > pageSize {
+		models = models[:pageSize]
+		last := reflect.ValueOf(models[pageSize-1]).Elem()
+		tm := repo.db.Mapper.TypeMap(last.Type())
+		cursor := make([]any, len(order))
+		for i, col := range order {
+			// Read only access keeps nil pointers of nullable columns instead of allocating them.
+			cursor[i] = reflectx.FieldByIndexesReadOnly(last, tm.GetByPath(col).Index).Interface()
+		}
+		var err error
+		token, err = encodePageToken(cursor)
+		if err != nil {
+			return nil, "", err
 		}
 	}
 
-	if err := errors.Join(multi...); err != nil {
-		return results, err
+	if err := runAfterLoad(ctx, repo.cnf.Hooks, models...); err != nil {
+		return nil, "", err
 	}
-	return results, nil
+	return models, token, nil
 }
 
-func (repo *RepoGeneric[T]) getPK(model *T) reflect.Value {
-	v := reflect.ValueOf(model).Elem()
-	return v.FieldByName(repo.cnf.PrimaryKey)
+func (repo *RepoGeneric[T]) Get(ctx context.Context, key any) (*T, error) {
+	return repo.store().get(ctx, key)
+}
+
+// GetMulti returns the models of the keys in the same order. Keys must be a slice. If some of
+// them do not exist it returns a joined error with a MissingKeyError for each one of them.
+func (repo *RepoGeneric[T]) GetMulti(ctx context.Context, keys any) ([]*T, error) {
+	return repo.store().getMulti(ctx, keys)
 }
 
 func (repo *RepoGeneric[T]) Query(ctx context.Context, query string, args ...interface{}) (*T, error) {
-	query = normalizeQuery(query)
-	repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoGeneric.Query"), slog.String("q", query))
-	var model T
-	if err := repo.db.GetContext(ctx, &model, query, args...); err != nil {
-		return nil, fmt.Errorf("cannot execute query: %w", err)
-	}
-	return &model, nil
+	return repo.store().query(ctx, query, args...)
 }
 
 func (repo *RepoGeneric[T]) QueryList(ctx context.Context, query string, args ...interface{}) ([]*T, error) {
-	query = normalizeQuery(query)
-	repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoGeneric.QueryList"), slog.String("q", query))
-	var models []*T
-	if err := repo.db.SelectContext(ctx, &models, query, args...); err != nil {
-		return nil, fmt.Errorf("cannot execute query: %w", err)
-	}
-	return models, nil
+	return repo.store().queryList(ctx, query, args...)
 }
 
-func (repo *RepoGeneric[T]) QueryMap(ctx context.Context, query string, args ...interface{}) (map[string]*T, error) {
-	repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoGeneric.QueryMap"), slog.String("q", query))
-	var model []*T
-	if err := repo.db.SelectContext(ctx, &model, query, args...); err != nil {
-		return nil, fmt.Errorf("cannot execute query: %w", err)
+// QueryMap runs the query and returns the models indexed by their primary key.
+func (repo *RepoGeneric[T]) QueryMap(ctx context.Context, query string, args ...interface{}) (map[any]*T, error) {
+	return repo.store().queryMap(ctx, query, args...)
+}
+
+func (repo *RepoGeneric[T]) DeleteKey(ctx context.Context, key any) error {
+	return repo.inTx(ctx, func(tx *Tx[T]) error {
+		return tx.DeleteKey(ctx, key)
+	})
+}
+
+func (repo *RepoGeneric[T]) Delete(ctx context.Context, model *T) error {
+	return repo.inTx(ctx, func(tx *Tx[T]) error {
+		return tx.Delete(ctx, model)
+	})
+}
+
+func (repo *RepoGeneric[T]) Exists(ctx context.Context, key any) (bool, error) {
+	return repo.store().exists(ctx, key)
+}
+
+// ExistsQuery prepares a query to check if a key exists, with one named argument for each
+// primary key column. Repositories with a Tenant column also need the tenant as a named argument,
+// unless it was bound with ForTenant.
+func (repo *RepoGeneric[T]) ExistsQuery() *Query[bool] {
+	pending := append([]string(nil), repo.cnf.PrimaryKeys...)
+	if repo.cnf.Tenant != "" && !slices.Contains(pending, repo.cnf.Tenant) {
+		pending = append(pending, repo.cnf.Tenant)
+	}
+	var conds []string
+	for _, col := range pending {
+		conds = append(conds, col+" = ?")
 	}
 
-	keyed := make(map[string]*T)
-	for _, m := range model {
-		keyed[repo.getPK(m).String()] = m
+	// The tenant is bound with the rest of the arguments when running the query, instead of
+	// being resolved by the store.
+	s := repo.store()
+	s.cnf.Tenant = ""
+	where, _, _ := s.where(context.Background(), conds, nil)
+	q := NewQuery[bool](repo, fmt.Sprintf("SELECT COUNT(*) > 0 FROM %s%s", repo.cnf.Table, where), pending)
+	q.order = pending
+	if repo.cnf.Tenant != "" && repo.tenant != nil {
+		q.Bind(sql.Named(repo.cnf.Tenant, repo.tenant))
 	}
+	return q
+}
 
-	return keyed, nil
+// History returns the changes of the row with the key recorded in the audit table. Repositories
+// with a Tenant column only return the changes made while the row belonged to the tenant.
+func (repo *RepoGeneric[T]) History(ctx context.Context, key any) ([]*AuditEntry, error) {
+	return repo.store().history(ctx, key)
 }
 
-func (repo *RepoGeneric[T]) DeleteKey(ctx context.Context, key string) error {
-	q := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", repo.cnf.Table, repo.cnf.PrimaryKey)
-	repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoGeneric.DeleteKey"), slog.String("q", q), slog.String("key", key))
-	if _, err := repo.db.ExecContext(ctx, q, key); err != nil {
-		return fmt.Errorf("cannot execute query: %w", err)
-	}
-	return nil
+func (repo *RepoGeneric[T]) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return repo.store().exec(ctx, query, args...)
 }
 
-func (repo *RepoGeneric[T]) Delete(ctx context.Context, model *T) error {
-	cols, values := listCols(repo.db, model)
-	for index, col := range cols {
-		if col != repo.cnf.PrimaryKey {
-			continue
-		}
+// QueryIter streams the results of the query one row at a time instead of loading them all in memory.
+func (repo *RepoGeneric[T]) QueryIter(ctx context.Context, query string, args ...interface{}) Seq[T] {
+	return newSeq[T](ctx, repo.db, repo.cnf, "RepoGeneric.QueryIter", normalizeQuery(query), args)
+}
 
-		q := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", repo.cnf.Table, repo.cnf.PrimaryKey)
-		repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoGeneric.Delete"), slog.String("q", q), slog.Any("key", values[index]))
-		if _, err := repo.db.ExecContext(ctx, q, values[index]); err != nil {
-			return fmt.Errorf("cannot execute query: %w", err)
+// ListIter streams all the rows of the table one at a time instead of loading them all in memory.
+func (repo *RepoGeneric[T]) ListIter(ctx context.Context) Seq[T] {
+	var single T
+	cols, _ := listCols(repo.db, single)
+	where, args, err := repo.store().where(ctx, nil, nil)
+	if err != nil {
+		return func(yield func(*T, error) bool) {
+			yield(nil, err)
 		}
-		return nil
 	}
-	return fmt.Errorf("cannot find primary key: %s", repo.cnf.PrimaryKey)
+	q := fmt.Sprintf("SELECT %s FROM %s%s", strings.Join(cols, ","), repo.cnf.Table, where)
+	return newSeq[T](ctx, repo.db, repo.cnf, "RepoGeneric.ListIter", q, args)
 }
 
-func (repo *RepoGeneric[T]) Exists(ctx context.Context, key string) (bool, error) {
-	if key == "" {
-		return false, nil
+// DeleteMulti removes the rows with the keys and returns the number of deleted rows. Keys must
+// be a slice.
+func (repo *RepoGeneric[T]) DeleteMulti(ctx context.Context, keys any) (int64, error) {
+	var n int64
+	err := repo.inTx(ctx, func(tx *Tx[T]) error {
+		var err error
+		n, err = tx.DeleteMulti(ctx, keys)
+		return err
+	})
+	if err != nil {
+		return 0, err
 	}
+	return n, nil
+}
 
-	q := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ?", repo.cnf.Table, repo.cnf.PrimaryKey)
-	repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoGeneric.Exists"), slog.String("q", q), slog.String("key", key))
-	var count int64
-	if err := repo.db.GetContext(ctx, &count, q, key); err != nil {
-		return false, fmt.Errorf("cannot execute query: %w", err)
+// DeleteWhere removes the rows matching the WHERE condition and returns the number of deleted rows.
+func (repo *RepoGeneric[T]) DeleteWhere(ctx context.Context, where string, args ...interface{}) (int64, error) {
+	var n int64
+	err := repo.inTx(ctx, func(tx *Tx[T]) error {
+		var err error
+		n, err = tx.DeleteWhere(ctx, where, args...)
+		return err
+	})
+	if err != nil {
+		return 0, err
 	}
-	return count > 0, nil
+	return n, nil
 }
 
-func (repo *RepoGeneric[T]) ExistsQuery() *Query[bool] {
-	q := fmt.Sprintf("SELECT COUNT(*) > 0 FROM %s WHERE %s = :%s", repo.cnf.Table, repo.cnf.PrimaryKey, repo.cnf.PrimaryKey)
-	return NewQuery[bool](repo, q, []string{repo.cnf.PrimaryKey})
+// Restore clears the soft delete mark of the row with the key. It fails with a MissingKeyError
+// if the key does not exist.
+func (repo *RepoGeneric[T]) Restore(ctx context.Context, key any) error {
+	return repo.inTx(ctx, func(tx *Tx[T]) error {
+		return tx.Restore(ctx, key)
+	})
 }
 
-func (repo *RepoGeneric[T]) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	query = normalizeQuery(query)
-	repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoGeneric.Exec"), slog.String("q", query))
-	result, err := repo.db.ExecContext(ctx, query, args...)
+// Purge permanently removes the row with the key, even if it was soft deleted before.
+func (repo *RepoGeneric[T]) Purge(ctx context.Context, key any) error {
+	return repo.inTx(ctx, func(tx *Tx[T]) error {
+		return tx.Purge(ctx, key)
+	})
+}
+
+// PurgeDeleted permanently removes the rows soft deleted before the time and returns the number
+// of removed rows.
+func (repo *RepoGeneric[T]) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	err := repo.inTx(ctx, func(tx *Tx[T]) error {
+		var err error
+		n, err = tx.PurgeDeleted(ctx, before)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("cannot execute query: %w", err)
+		return 0, err
 	}
-	return result, nil
+	return n, nil
 }
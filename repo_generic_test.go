@@ -3,7 +3,9 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -13,12 +15,13 @@ func TestGenericCount(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
-	_, err := db.Exec("INSERT INTO TestModels (Name, Value) VALUES (?, ?)", "foo-name", "foo-value")
+	_, err = db.Exec("INSERT INTO TestModels (Name, Value) VALUES (?, ?)", "foo-name", "foo-value")
 	require.NoError(t, err)
 	_, err = db.Exec("INSERT INTO TestModels (Name, Value) VALUES (?, ?)", "bar-name", "bar-value")
 	require.NoError(t, err)
@@ -33,10 +36,11 @@ func TestGenericPut(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	foo := &testModel{
 		Name:  "foo-name",
@@ -55,12 +59,13 @@ func TestGenericGet(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
-	_, err := db.Exec("INSERT INTO TestModels (Name, Value) VALUES (?, ?)", "foo-name", "foo-value")
+	_, err = db.Exec("INSERT INTO TestModels (Name, Value) VALUES (?, ?)", "foo-name", "foo-value")
 	require.NoError(t, err)
 
 	other, err := repo.Get(ctx, "foo-name")
@@ -74,10 +79,11 @@ func TestGenericGetNotFound(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	other, err := repo.Get(ctx, "foo-name")
 	require.Nil(t, other)
@@ -89,10 +95,11 @@ func TestGenericGetEmptyKeyNotFound(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	other, err := repo.Get(ctx, "")
 	require.Nil(t, other)
@@ -105,10 +112,11 @@ func TestGenericQuery(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
 	require.NoError(t, repo.Put(ctx, &testModel{Name: "bar-name", Value: "bar-value"}))
@@ -124,10 +132,11 @@ func TestGenericQueryMap(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
 	require.NoError(t, repo.Put(ctx, &testModel{Name: "bar-name", Value: "bar-value"}))
@@ -146,10 +155,11 @@ func TestGenericGetMulti(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
 	require.NoError(t, repo.Put(ctx, &testModel{Name: "bar-name", Value: "bar-value"}))
@@ -172,10 +182,11 @@ func TestGenericGetMultiNotFound(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
 	require.NoError(t, repo.Put(ctx, &testModel{Name: "baz-name", Value: "baz-value"}))
@@ -203,10 +214,11 @@ func TestGenericGetMultiEmpty(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	results, err := repo.GetMulti(ctx, []string{})
 	require.NoError(t, err)
@@ -218,10 +230,11 @@ func TestGenericGetMultiNil(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	results, err := repo.GetMulti(ctx, nil)
 	require.NoError(t, err)
@@ -233,10 +246,11 @@ func TestGenericDeleteKey(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
 
@@ -252,10 +266,11 @@ func TestGenericDeleteKeyNotExists(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	require.NoError(t, repo.DeleteKey(ctx, "foo-name"))
 
@@ -269,10 +284,11 @@ func TestGenericDelete(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	model := &testModel{Name: "foo-name", Value: "foo-value"}
 	require.NoError(t, repo.Put(ctx, model))
@@ -289,10 +305,11 @@ func TestGenericDeleteDoubleNotExists(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	model := &testModel{Name: "foo-name", Value: "foo-value"}
 	require.NoError(t, repo.Put(ctx, model))
@@ -306,10 +323,11 @@ func TestGenericExists(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
 
@@ -327,10 +345,11 @@ func TestGenericExistsShortcircuits(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	require.NoError(t, repo.Put(ctx, &testModel{Name: "", Value: "foo-value"}))
 
@@ -344,10 +363,11 @@ func TestGenericExec(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	result, err := repo.Exec(ctx, "INSERT INTO TestModels (Name, Value) VALUES (?, ?)", "foo-name", "foo-value")
 	require.NoError(t, err)
@@ -366,10 +386,11 @@ func TestGenericExistsQuery(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
 
@@ -382,15 +403,36 @@ func TestGenericExistsQuery(t *testing.T) {
 	require.False(t, exists)
 }
 
+func TestGenericNewQueryPositional(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+
+	q := NewQuery[bool](repo, "SELECT COUNT(*) > 0 FROM TestModels WHERE Name = ? AND Value = ?", []string{"name", "value"})
+	q.Bind(sql.Named("name", "foo-name"))
+	exists, err := q.QueryValue(ctx, sql.Named("value", "foo-value"))
+	require.NoError(t, err)
+	require.True(t, exists)
+}
+
 func TestGenericBeginTxPut(t *testing.T) {
 	ctx := context.Background()
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoGeneric(db, RepoConfig[testModel]{
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	tx, err := repo.BeginTx(ctx)
 	require.NoError(t, err)
@@ -405,3 +447,1242 @@ func TestGenericBeginTxPut(t *testing.T) {
 	require.NoError(t, err)
 	require.EqualValues(t, count, 2)
 }
+
+func TestGenericListPage(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "c-name", Value: "c-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "a-name", Value: "a-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "e-name", Value: "e-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "b-name", Value: "b-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "d-name", Value: "d-value"}))
+
+	results, token, err := repo.ListPage(ctx, "", 2)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, results[0].Name, "a-name")
+	require.Equal(t, results[1].Name, "b-name")
+	require.NotEmpty(t, token)
+
+	results, token, err = repo.ListPage(ctx, token, 2)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, results[0].Name, "c-name")
+	require.Equal(t, results[1].Name, "d-name")
+	require.NotEmpty(t, token)
+
+	results, token, err = repo.ListPage(ctx, token, 2)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, results[0].Name, "e-name")
+	require.Empty(t, token)
+}
+
+func TestGenericListPageOrder(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+		PageOrder:  "Value",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "a-name", Value: "2"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "b-name", Value: "1"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "c-name", Value: "1"}))
+
+	results, token, err := repo.ListPage(ctx, "", 2)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, results[0].Name, "b-name")
+	require.Equal(t, results[1].Name, "c-name")
+
+	results, token, err = repo.ListPage(ctx, token, 2)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, results[0].Name, "a-name")
+	require.Empty(t, token)
+}
+
+func TestGenericListPageOrderNull(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testSoftDeleted]{
+		Table:      "TestSoftDeleted",
+		PrimaryKey: "Name",
+		PageOrder:  "DeletedAt",
+	})
+	require.NoError(t, err)
+
+	first := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	require.NoError(t, repo.Put(ctx, &testSoftDeleted{Name: "a-name"}))
+	require.NoError(t, repo.Put(ctx, &testSoftDeleted{Name: "b-name"}))
+	require.NoError(t, repo.Put(ctx, &testSoftDeleted{Name: "c-name"}))
+	require.NoError(t, repo.Put(ctx, &testSoftDeleted{Name: "d-name", DeletedAt: &second}))
+	require.NoError(t, repo.Put(ctx, &testSoftDeleted{Name: "e-name", DeletedAt: &first}))
+	require.NoError(t, repo.Put(ctx, &testSoftDeleted{Name: "f-name", DeletedAt: &first}))
+
+	for _, size := range []int{1, 2, 4} {
+		var names []string
+		var token string
+		for {
+			results, next, err := repo.ListPage(ctx, token, size)
+			require.NoError(t, err)
+			for _, result := range results {
+				names = append(names, result.Name)
+			}
+			if next == "" {
+				break
+			}
+			token = next
+		}
+		require.Equal(t, names, []string{"a-name", "b-name", "c-name", "e-name", "f-name", "d-name"}, size)
+	}
+
+	results, _, err := repo.ListPage(ctx, "", 1)
+	require.NoError(t, err)
+	require.Nil(t, results[0].DeletedAt)
+}
+
+func TestGenericListPageInvalidToken(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	_, _, err = repo.ListPage(ctx, "not a token", 2)
+	require.ErrorIs(t, err, ErrInvalidPageToken)
+}
+
+func TestGenericListIter(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "bar-name", Value: "bar-value"}))
+
+	var names []string
+	repo.ListIter(ctx)(func(model *testModel, err error) bool {
+		require.NoError(t, err)
+		names = append(names, model.Name)
+		return true
+	})
+	require.ElementsMatch(t, names, []string{"foo-name", "bar-name"})
+}
+
+func TestGenericQueryIterStopEarly(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "bar-name", Value: "bar-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "baz-name", Value: "baz-value"}))
+
+	var calls int
+	repo.QueryIter(ctx, "SELECT * FROM TestModels ORDER BY Name")(func(model *testModel, err error) bool {
+		require.NoError(t, err)
+		require.Equal(t, model.Name, "bar-name")
+		calls++
+		return false
+	})
+	require.Equal(t, calls, 1)
+
+	// The cursor must be released for the single connection of the in-memory database.
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, count, 3)
+}
+
+func TestGenericQueryIterCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+	cancel()
+
+	var last error
+	repo.QueryIter(ctx, "SELECT * FROM TestModels")(func(model *testModel, err error) bool {
+		last = err
+		return true
+	})
+	require.ErrorIs(t, last, context.Canceled)
+}
+
+func TestGenericPutMulti(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.PutMulti(ctx, []*testModel{
+		{Name: "foo-name", Value: "foo-value"},
+		{Name: "bar-name", Value: "bar-value"},
+		{Name: "baz-name", Value: "baz-value"},
+	}))
+
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, count, 3)
+}
+
+func TestGenericPutMultiHookError(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+		Hooks: Hooks[testModel]{
+			BeforePut: []Hook[testModel]{
+				func(ctx context.Context, model *testModel) error {
+					if model.Value == "" {
+						return errors.New("empty value")
+					}
+					return nil
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	err = repo.PutMulti(ctx, []*testModel{
+		{Name: "foo-name", Value: "foo-value"},
+		{Name: "bar-name"},
+		{Name: "baz-name", Value: "baz-value"},
+	})
+	var multi unwrapper
+	require.ErrorAs(t, err, &multi)
+	errs := multi.Unwrap()
+	require.Len(t, errs, 1)
+	var putErr *PutError
+	require.ErrorAs(t, errs[0], &putErr)
+	require.Equal(t, putErr.Index, 1)
+	require.Equal(t, putErr.Key, "bar-name")
+	require.EqualError(t, putErr.Err, "global before put hook: empty value")
+
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, count, 0)
+}
+
+func TestGenericDeleteMulti(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "bar-name", Value: "bar-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "baz-name", Value: "baz-value"}))
+
+	n, err := repo.DeleteMulti(ctx, []string{"foo-name", "baz-name", "qux-name"})
+	require.NoError(t, err)
+	require.EqualValues(t, n, 2)
+
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, count, 1)
+}
+
+func TestGenericDeleteWhere(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "bar-name", Value: "bar-value"}))
+
+	n, err := repo.DeleteWhere(ctx, "Value = ?", "bar-value")
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+
+	n, err = repo.DeleteWhere(ctx, "Value = ?", "bar-value")
+	require.NoError(t, err)
+	require.EqualValues(t, n, 0)
+
+	_, err = repo.DeleteWhere(ctx, "")
+	require.EqualError(t, err, "empty where condition")
+
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, count, 1)
+}
+
+func TestGenericBeginTxDeleteMulti(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+
+	tx, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	require.NoError(t, tx.Put(ctx, &testModel{Name: "bar-name", Value: "bar-value"}))
+	n, err := tx.DeleteMulti(ctx, []string{"foo-name"})
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+
+	require.NoError(t, tx.Rollback())
+
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, count, 1)
+}
+
+func TestGenericPutVersion(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testVersioned]{
+		Table:      "TestVersioned",
+		PrimaryKey: "Name",
+		Version:    "Version",
+	})
+	require.NoError(t, err)
+
+	model := &testVersioned{Name: "foo-name", Value: "foo-value"}
+	require.NoError(t, repo.Put(ctx, model))
+	require.EqualValues(t, model.Version, 1)
+
+	model.Value = "updated"
+	require.NoError(t, repo.Put(ctx, model))
+	require.EqualValues(t, model.Version, 2)
+
+	other, err := repo.Get(ctx, "foo-name")
+	require.NoError(t, err)
+	require.Equal(t, other.Value, "updated")
+	require.EqualValues(t, other.Version, 2)
+}
+
+func TestGenericPutVersionConflict(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testVersioned]{
+		Table:      "TestVersioned",
+		PrimaryKey: "Name",
+		Version:    "Version",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testVersioned{Name: "foo-name", Value: "foo-value"}))
+
+	first, err := repo.Get(ctx, "foo-name")
+	require.NoError(t, err)
+	second, err := repo.Get(ctx, "foo-name")
+	require.NoError(t, err)
+
+	first.Value = "first"
+	require.NoError(t, repo.Put(ctx, first))
+
+	second.Value = "second"
+	err = repo.Put(ctx, second)
+	var conflict *ConflictError
+	require.ErrorAs(t, err, &conflict)
+	require.Equal(t, conflict.Key, "foo-name")
+	require.EqualValues(t, second.Version, 1)
+
+	err = repo.Put(ctx, &testVersioned{Name: "foo-name", Value: "new"})
+	require.ErrorAs(t, err, &conflict)
+
+	other, err := repo.Get(ctx, "foo-name")
+	require.NoError(t, err)
+	require.Equal(t, other.Value, "first")
+}
+
+func TestGenericInsert(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Insert(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+
+	err = repo.Insert(ctx, &testModel{Name: "foo-name", Value: "other"})
+	var exists *AlreadyExistsError
+	require.ErrorAs(t, err, &exists)
+	require.Equal(t, exists.Key, "foo-name")
+
+	other, err := repo.Get(ctx, "foo-name")
+	require.NoError(t, err)
+	require.Equal(t, other.Value, "foo-value")
+}
+
+func TestGenericUpdate(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	err = repo.Update(ctx, &testModel{Name: "foo-name", Value: "foo-value"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	var missing *MissingKeyError
+	require.ErrorAs(t, err, &missing)
+	require.Equal(t, missing.Key, "foo-name")
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, repo.Update(ctx, &testModel{Name: "foo-name", Value: "updated"}))
+
+	other, err := repo.Get(ctx, "foo-name")
+	require.NoError(t, err)
+	require.Equal(t, other.Value, "updated")
+}
+
+func TestGenericUpdateOnlyKeys(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testMember]{
+		Table: "TestMembers",
+	})
+	require.NoError(t, err)
+
+	err = repo.Update(ctx, &testMember{GroupID: "foo-group", UserID: "foo-user"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	var missing *MissingKeyError
+	require.ErrorAs(t, err, &missing)
+	require.Equal(t, missing.Key, Key{"foo-group", "foo-user"})
+
+	require.NoError(t, repo.Put(ctx, &testMember{GroupID: "foo-group", UserID: "foo-user"}))
+	require.NoError(t, repo.Update(ctx, &testMember{GroupID: "foo-group", UserID: "foo-user"}))
+}
+
+func TestGenericUpdateVersionConflict(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testVersioned]{
+		Table:      "TestVersioned",
+		PrimaryKey: "Name",
+		Version:    "Version",
+	})
+	require.NoError(t, err)
+
+	model := &testVersioned{Name: "foo-name", Value: "foo-value"}
+	require.NoError(t, repo.Insert(ctx, model))
+	require.EqualValues(t, model.Version, 1)
+
+	require.NoError(t, repo.Update(ctx, model))
+	require.EqualValues(t, model.Version, 2)
+
+	err = repo.Update(ctx, &testVersioned{Name: "foo-name", Value: "stale", Version: 1})
+	var conflict *ConflictError
+	require.ErrorAs(t, err, &conflict)
+}
+
+func TestGenericUpsertKeepsChildren(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("PRAGMA foreign_keys = ON")
+	require.NoError(t, err)
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+	children, err := NewRepoGeneric(db, RepoConfig[testChild]{
+		Table:      "TestChildren",
+		PrimaryKey: "ID",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Upsert(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, children.Put(ctx, &testChild{ID: "foo-child", Parent: "foo-name"}))
+
+	require.NoError(t, repo.Upsert(ctx, &testModel{Name: "foo-name", Value: "updated"}))
+
+	other, err := repo.Get(ctx, "foo-name")
+	require.NoError(t, err)
+	require.Equal(t, other.Value, "updated")
+
+	n, err := children.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "replaced"}))
+
+	n, err = children.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 0)
+}
+
+func TestGenericUpdateFields(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testVersioned]{
+		Table:      "TestVersioned",
+		PrimaryKey: "Name",
+		Version:    "Version",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testVersioned{Name: "foo-name", Value: "foo-value"}))
+
+	require.NoError(t, repo.UpdateFields(ctx, "foo-name", map[string]any{"Value": "updated"}))
+
+	other, err := repo.Get(ctx, "foo-name")
+	require.NoError(t, err)
+	require.Equal(t, other.Value, "updated")
+	require.EqualValues(t, other.Version, 2)
+}
+
+func TestGenericUpdateFieldsErrors(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	err = repo.UpdateFields(ctx, "foo-name", map[string]any{"Value": "updated"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	var missing *MissingKeyError
+	require.ErrorAs(t, err, &missing)
+	require.Equal(t, missing.Key, "foo-name")
+
+	err = repo.UpdateFields(ctx, "foo-name", map[string]any{"Vaule": "updated"})
+	require.EqualError(t, err, `unknown column "Vaule" in table TestModels`)
+
+	err = repo.UpdateFields(ctx, "foo-name", map[string]any{"Name": "bar-name"})
+	require.EqualError(t, err, `cannot update column "Name"`)
+}
+
+func TestGenericTimestamps(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	now := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	repo, err := NewRepoGeneric(db, RepoConfig[testTimestamps]{
+		Table:      "TestTimestamps",
+		PrimaryKey: "Name",
+		CreatedAt:  "CreatedAt",
+		UpdatedAt:  "UpdatedAt",
+		Clock:      func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	model := &testTimestamps{Name: "foo-name", Value: "foo-value"}
+	require.NoError(t, repo.Put(ctx, model))
+	require.Equal(t, model.CreatedAt, now)
+	require.Equal(t, model.UpdatedAt, now)
+
+	created := now
+	now = now.Add(time.Hour)
+	require.NoError(t, repo.Put(ctx, &testTimestamps{Name: "foo-name", Value: "updated"}))
+
+	other, err := repo.Get(ctx, "foo-name")
+	require.NoError(t, err)
+	require.True(t, other.CreatedAt.Equal(created))
+	require.True(t, other.UpdatedAt.Equal(now))
+
+	now = now.Add(time.Hour)
+	require.NoError(t, repo.UpdateFields(ctx, "foo-name", map[string]any{"Value": "partial"}))
+
+	other, err = repo.Get(ctx, "foo-name")
+	require.NoError(t, err)
+	require.True(t, other.CreatedAt.Equal(created))
+	require.True(t, other.UpdatedAt.Equal(now))
+}
+
+func TestGenericSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	now := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	repo, err := NewRepoGeneric(db, RepoConfig[testSoftDeleted]{
+		Table:      "TestSoftDeleted",
+		PrimaryKey: "Name",
+		SoftDelete: "DeletedAt",
+		Clock:      func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testSoftDeleted{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, repo.Put(ctx, &testSoftDeleted{Name: "bar-name", Value: "bar-value"}))
+
+	require.NoError(t, repo.DeleteKey(ctx, "foo-name"))
+
+	_, err = repo.Get(ctx, "foo-name")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	exists, err := repo.Exists(ctx, "foo-name")
+	require.NoError(t, err)
+	require.False(t, exists)
+	exists, err = repo.ExistsQuery().QueryValue(ctx, sql.Named("Name", "foo-name"))
+	require.NoError(t, err)
+	require.False(t, exists)
+	n, err := repo.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+	models, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+	_, err = repo.GetMulti(ctx, []string{"foo-name", "bar-name"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	deleted, err := repo.WithDeleted().Get(ctx, "foo-name")
+	require.NoError(t, err)
+	require.NotNil(t, deleted.DeletedAt)
+	require.True(t, deleted.DeletedAt.Equal(now))
+	n, err = repo.WithDeleted().Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 2)
+
+	var count int64
+	require.NoError(t, db.Get(&count, "SELECT COUNT(*) FROM TestSoftDeleted"))
+	require.EqualValues(t, count, 2)
+}
+
+func TestGenericSoftDeleteRestore(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testSoftDeleted]{
+		Table:      "TestSoftDeleted",
+		PrimaryKey: "Name",
+		SoftDelete: "DeletedAt",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testSoftDeleted{Name: "foo-name", Value: "foo-value"}))
+	n, err := repo.DeleteWhere(ctx, "Value = ? OR Value = ?", "foo-value", "other")
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+
+	n, err = repo.DeleteWhere(ctx, "Value = ?", "foo-value")
+	require.NoError(t, err)
+	require.EqualValues(t, n, 0)
+
+	require.NoError(t, repo.Restore(ctx, "foo-name"))
+
+	model, err := repo.Get(ctx, "foo-name")
+	require.NoError(t, err)
+	require.Nil(t, model.DeletedAt)
+
+	err = repo.Restore(ctx, "bar-name")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestGenericSoftDeletePurge(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	now := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	repo, err := NewRepoGeneric(db, RepoConfig[testSoftDeleted]{
+		Table:      "TestSoftDeleted",
+		PrimaryKey: "Name",
+		SoftDelete: "DeletedAt",
+		Clock:      func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testSoftDeleted{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, repo.Put(ctx, &testSoftDeleted{Name: "bar-name", Value: "bar-value"}))
+	require.NoError(t, repo.Put(ctx, &testSoftDeleted{Name: "baz-name", Value: "baz-value"}))
+
+	require.NoError(t, repo.DeleteKey(ctx, "foo-name"))
+	now = now.Add(24 * time.Hour)
+	require.NoError(t, repo.DeleteKey(ctx, "bar-name"))
+
+	n, err := repo.PurgeDeleted(ctx, now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+
+	require.NoError(t, repo.Purge(ctx, "baz-name"))
+
+	n, err = repo.WithDeleted().Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+
+	model, err := repo.WithDeleted().Get(ctx, "bar-name")
+	require.NoError(t, err)
+	require.NotNil(t, model.DeletedAt)
+}
+
+func TestGenericAudit(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	require.NoError(t, AuditMigration("TestAudit")(ctx, db))
+
+	now := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+		Audit:      "TestAudit",
+		Clock:      func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	ctx = WithActor(ctx, "foo-actor")
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "bar-value"}))
+	require.NoError(t, repo.UpdateFields(ctx, "foo-name", map[string]any{"Value": "baz-value"}))
+	require.NoError(t, repo.DeleteKey(ctx, "foo-name"))
+	require.NoError(t, repo.DeleteKey(ctx, "foo-name"))
+
+	history, err := repo.History(ctx, "foo-name")
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+
+	require.Equal(t, history[0].Table, "TestModels")
+	require.Equal(t, history[0].Key, "foo-name")
+	require.Equal(t, history[0].Operation, AuditInsert)
+	require.Equal(t, history[0].Actor, "foo-actor")
+	require.True(t, history[0].Time.Equal(now))
+	require.Nil(t, history[0].Before)
+	require.JSONEq(t, `{"Name": "foo-name", "Value": "foo-value"}`, string(history[0].After))
+
+	require.Equal(t, history[1].Operation, AuditUpdate)
+	require.JSONEq(t, `{"Name": "foo-name", "Value": "foo-value"}`, string(history[1].Before))
+	require.JSONEq(t, `{"Name": "foo-name", "Value": "bar-value"}`, string(history[1].After))
+
+	require.Equal(t, history[2].Operation, AuditUpdate)
+	require.JSONEq(t, `{"Name": "foo-name", "Value": "bar-value"}`, string(history[2].Before))
+	require.JSONEq(t, `{"Name": "foo-name", "Value": "baz-value"}`, string(history[2].After))
+
+	require.Equal(t, history[3].Operation, AuditDelete)
+	require.JSONEq(t, `{"Name": "foo-name", "Value": "baz-value"}`, string(history[3].Before))
+	require.Nil(t, history[3].After)
+}
+
+func TestGenericAuditSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	require.NoError(t, AuditMigration("TestAudit")(ctx, db))
+
+	now := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	repo, err := NewRepoGeneric(db, RepoConfig[testSoftDeleted]{
+		Table:      "TestSoftDeleted",
+		PrimaryKey: "Name",
+		SoftDelete: "DeletedAt",
+		Audit:      "TestAudit",
+		Clock:      func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testSoftDeleted{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, repo.DeleteKey(ctx, "foo-name"))
+	require.NoError(t, repo.Restore(ctx, "foo-name"))
+	require.NoError(t, repo.Purge(ctx, "foo-name"))
+
+	history, err := repo.History(ctx, "foo-name")
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+
+	require.Equal(t, history[1].Operation, AuditDelete)
+	require.JSONEq(t, string(history[1].Before), `{"Name":"foo-name","Value":"foo-value","DeletedAt":null}`)
+	require.JSONEq(t, string(history[1].After), `{"Name":"foo-name","Value":"foo-value","DeletedAt":"2024-01-01T10:00:00Z"}`)
+
+	require.Equal(t, history[2].Operation, AuditRestore)
+	require.JSONEq(t, string(history[2].Before), string(history[1].After))
+
+	require.Equal(t, history[3].Operation, AuditDelete)
+	require.Nil(t, history[3].After)
+}
+
+type testAuditTagged struct {
+	Code        string `sqlite:"code,pk"`
+	DisplayName string `sqlite:"display_name" json:"-"`
+	Status      string `sqlite:"status" json:"state"`
+	Revision    int64  `sqlite:"revision,readonly"`
+	Secret      string `sqlite:"-"`
+}
+
+func TestGenericAuditColumns(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	require.NoError(t, AuditMigration("TestAudit")(ctx, db))
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testAuditTagged]{
+		Table: "test_tagged",
+		Audit: "TestAudit",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testAuditTagged{Code: "foo-code", DisplayName: "foo-name", Status: "active", Secret: "foo-secret"}))
+
+	history, err := repo.History(ctx, "foo-code")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.JSONEq(t, string(history[0].After), `{"code":"foo-code","display_name":"foo-name","status":"active","revision":7}`)
+}
+
+func TestGenericAuditRollback(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	require.NoError(t, AuditMigration("TestAudit")(ctx, db))
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+		Audit:      "TestAudit",
+	})
+	require.NoError(t, err)
+
+	err = repo.RunInTx(ctx, func(tx *Tx[testModel]) error {
+		require.NoError(t, tx.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+		history, err := tx.History(ctx, "foo-name")
+		require.NoError(t, err)
+		require.Len(t, history, 1)
+		return errNotAllowed
+	})
+	require.ErrorIs(t, err, errNotAllowed)
+
+	history, err := repo.History(ctx, "foo-name")
+	require.NoError(t, err)
+	require.Empty(t, history)
+}
+
+func TestGenericAuditNotConfigured(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	_, err = repo.History(ctx, "foo-name")
+	require.Error(t, err)
+}
+
+func TestGenericStructTags(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testTagged]{
+		Table: "test_tagged",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testTagged{Code: "foo-code", DisplayName: "foo-name", Revision: 3, Ignored: "foo-ignored"}))
+	require.NoError(t, repo.Put(ctx, &testTagged{Code: "bar-code", DisplayName: "bar-name", Status: "archived"}))
+
+	model, err := repo.Get(ctx, "foo-code")
+	require.NoError(t, err)
+	require.Equal(t, model, &testTagged{Code: "foo-code", DisplayName: "foo-name", Status: "active", Revision: 7})
+
+	models, err := repo.Select().Filter("status", "=", "archived").Fetch(ctx)
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+	require.Equal(t, models[0].Code, "bar-code")
+
+	require.NoError(t, repo.UpdateFields(ctx, "foo-code", map[string]any{"display_name": "baz-name"}))
+	model, err = repo.Get(ctx, "foo-code")
+	require.NoError(t, err)
+	require.Equal(t, model.DisplayName, "baz-name")
+
+	require.Error(t, repo.UpdateFields(ctx, "foo-code", map[string]any{"revision": 1}))
+	require.Error(t, repo.UpdateFields(ctx, "foo-code", map[string]any{"Ignored": "foo"}))
+
+	_, err = db.Exec("UPDATE test_tagged SET revision = 42 WHERE code = 'foo-code'")
+	require.NoError(t, err)
+	model, err = repo.Get(ctx, "foo-code")
+	require.NoError(t, err)
+	model.DisplayName = "qux-name"
+	require.NoError(t, repo.Put(ctx, model))
+	model, err = repo.Get(ctx, "foo-code")
+	require.NoError(t, err)
+	require.Equal(t, model, &testTagged{Code: "foo-code", DisplayName: "qux-name", Status: "active", Revision: 42})
+
+	require.NoError(t, repo.Delete(ctx, model))
+	exists, err := repo.Exists(ctx, "foo-code")
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+type testLegacyTagged struct {
+	Code string `db:"code"`
+}
+
+type testLegacySameName struct {
+	Name  string `db:"Name"`
+	Value string
+}
+
+func TestGenericConfigValidation(t *testing.T) {
+	db := connectDB(t)
+	defer db.Close()
+
+	_, err := NewRepoGeneric(db, RepoConfig[testModel]{PrimaryKey: "Name"})
+	require.EqualError(t, err, `invalid repository config: invalid table name "" for model sqlite.testModel`)
+
+	_, err = NewRepoGeneric(db, RepoConfig[testModel]{Table: "TestModels; DROP TABLE TestModels", PrimaryKey: "Name"})
+	require.Error(t, err)
+	_, err = NewRepoGeneric(db, RepoConfig[testModel]{Table: `"Test" ; DROP TABLE TestModels; "Models"`, PrimaryKey: "Name"})
+	require.Error(t, err)
+	_, err = NewRepoGeneric(db, RepoConfig[testModel]{Table: "main.", PrimaryKey: "Name"})
+	require.Error(t, err)
+
+	_, err = NewRepoGeneric(db, RepoConfig[testModel]{Table: "TestModels"})
+	require.EqualError(t, err, `invalid repository config: table TestModels has no primary key configured`)
+
+	_, err = NewRepoGeneric(db, RepoConfig[testLegacyTagged]{Table: "test_tagged"})
+	require.EqualError(t, err, "invalid repository config: field Code of model sqlite.testLegacyTagged has a db struct tag, use the sqlite tag instead: `sqlite:\"code\"`")
+	_, err = NewRepoGeneric(db, RepoConfig[testLegacySameName]{Table: "TestModels", PrimaryKey: "Name"})
+	require.NoError(t, err)
+
+	_, err = NewRepoGeneric(db, RepoConfig[testModel]{Table: "TestModels", PrimaryKey: "Foo"})
+	require.EqualError(t, err, `invalid repository config: primary key column "Foo" of table TestModels is not a field of model sqlite.testModel`)
+
+	_, err = NewRepoGeneric(db, RepoConfig[testSoftDeleted]{Table: "TestSoftDeleted", PrimaryKey: "DeletedAt"})
+	require.EqualError(t, err, `invalid repository config: primary key column "DeletedAt" of table TestSoftDeleted must be a comparable value, not *time.Time`)
+
+	_, err = NewRepoGeneric(db, RepoConfig[testVersioned]{Table: "TestVersioned", PrimaryKey: "Name", Version: "Value"})
+	require.EqualError(t, err, `invalid repository config: version column "Value" of table TestVersioned must be an integer, not string`)
+
+	_, err = NewRepoGeneric(db, RepoConfig[testTimestamps]{Table: "TestTimestamps", PrimaryKey: "Name", CreatedAt: "Value"})
+	require.EqualError(t, err, `invalid repository config: created at column "Value" of table TestTimestamps must be a time.Time, not string`)
+
+	_, err = NewRepoGeneric(db, RepoConfig[testTimestamps]{Table: "TestTimestamps", PrimaryKey: "Name", SoftDelete: "UpdatedAt"})
+	require.EqualError(t, err, `invalid repository config: soft delete column "UpdatedAt" of table TestTimestamps must be a *time.Time or sql.NullTime, not time.Time`)
+
+	_, err = NewRepoGeneric(db, RepoConfig[testTagged]{Table: "test_tagged", PrimaryKey: "display_name"})
+	require.EqualError(t, err, `invalid repository config: table test_tagged configures primary key "display_name" but model sqlite.testTagged tags "code"`)
+
+	_, err = NewRepoGeneric(db, RepoConfig[string]{Table: "TestModels", PrimaryKey: "Name"})
+	require.EqualError(t, err, `invalid repository config: model string must be a struct`)
+}
+
+func TestGenericQualifiedTable(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	_, err := db.Exec(`CREATE TABLE "Order" (Name TEXT NOT NULL PRIMARY KEY, Value TEXT)`)
+	require.NoError(t, err)
+
+	for _, table := range []string{"main.TestModels", `"Order"`, `main."Order"`, "[Order]"} {
+		repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+			Table:      table,
+			PrimaryKey: "Name",
+		})
+		require.NoError(t, err, table)
+
+		require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: table}))
+		model, err := repo.Get(ctx, "foo-name")
+		require.NoError(t, err)
+		require.Equal(t, model.Value, table)
+		require.NoError(t, repo.DeleteKey(ctx, "foo-name"))
+	}
+}
+
+func TestGenericConfigTagPrimaryKey(t *testing.T) {
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testTagged]{Table: "test_tagged"})
+	require.NoError(t, err)
+	require.Equal(t, repo.cnf.PrimaryKey, "code")
+
+	_, err = NewRepoSingleton(db, RepoConfig[testModel]{Table: "TestModels", PrimaryKey: "Foo"})
+	require.Error(t, err)
+}
+
+func TestGenericIntegerKeys(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testInteger]{
+		Table:      "TestIntegers",
+		PrimaryKey: "ID",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testInteger{ID: 1, Value: "foo-value"}))
+	require.NoError(t, repo.Put(ctx, &testInteger{ID: 2, Value: "bar-value"}))
+
+	model, err := repo.Get(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, model.Value, "foo-value")
+
+	exists, err := repo.Exists(ctx, int64(2))
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	models, err := repo.GetMulti(ctx, []int{2, 3, 1})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.EqualError(t, err, "sqlite: cannot get 3: sql: no rows in result set")
+	var missing *MissingKeyError
+	require.ErrorAs(t, err, &missing)
+	require.Equal(t, missing.Key, int64(3))
+	require.Len(t, models, 3)
+	require.Equal(t, models[0].Value, "bar-value")
+	require.Nil(t, models[1])
+	require.Equal(t, models[2].Value, "foo-value")
+
+	results, err := repo.QueryMap(ctx, "SELECT * FROM TestIntegers")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, results[int64(1)].Value, "foo-value")
+
+	_, err = repo.Get(ctx, "1")
+	require.EqualError(t, err, "invalid key type string for primary key ID of type int64")
+
+	require.NoError(t, repo.DeleteKey(ctx, 1))
+	_, err = repo.Get(ctx, 1)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestGenericAutoIncrement(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testInteger]{
+		Table:         "TestAutoIncrement",
+		PrimaryKey:    "ID",
+		AutoIncrement: true,
+	})
+	require.NoError(t, err)
+
+	foo := &testInteger{Value: "foo-value"}
+	require.NoError(t, repo.Put(ctx, foo))
+	require.EqualValues(t, foo.ID, 1)
+
+	bar := &testInteger{Value: "bar-value"}
+	require.NoError(t, repo.Insert(ctx, bar))
+	require.EqualValues(t, bar.ID, 2)
+
+	baz := &testInteger{Value: "baz-value"}
+	require.NoError(t, repo.Upsert(ctx, baz))
+	require.EqualValues(t, baz.ID, 3)
+
+	foo.Value = "qux-value"
+	require.NoError(t, repo.Put(ctx, foo))
+	require.EqualValues(t, foo.ID, 1)
+
+	n, err := repo.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 3)
+
+	model, err := repo.Get(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, model.Value, "qux-value")
+}
+
+func TestGenericAutoIncrementStringKey(t *testing.T) {
+	db := connectDB(t)
+	defer db.Close()
+
+	_, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:         "TestModels",
+		PrimaryKey:    "Name",
+		AutoIncrement: true,
+	})
+	require.EqualError(t, err, `invalid repository config: auto increment primary key column "Name" of table TestModels must be an integer, not string`)
+}
+
+func TestGenericCompositeKeys(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testComposite]{
+		Table: "TestComposite",
+	})
+	require.NoError(t, err)
+	require.Equal(t, repo.cnf.PrimaryKeys, []string{"TenantID", "ID"})
+
+	require.NoError(t, repo.Put(ctx, &testComposite{TenantID: "foo", ID: 1, Value: "foo-1"}))
+	require.NoError(t, repo.Put(ctx, &testComposite{TenantID: "foo", ID: 2, Value: "foo-2"}))
+	require.NoError(t, repo.Put(ctx, &testComposite{TenantID: "bar", ID: 1, Value: "bar-1"}))
+	require.NoError(t, repo.Upsert(ctx, &testComposite{TenantID: "bar", ID: 1, Value: "bar-1-upsert"}))
+
+	model, err := repo.Get(ctx, Key{"bar", 1})
+	require.NoError(t, err)
+	require.Equal(t, model.Value, "bar-1-upsert")
+
+	exists, err := repo.Exists(ctx, Key{"bar", 2})
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	models, err := repo.GetMulti(ctx, []Key{{"foo", 2}, {"foo", 3}, {"bar", 1}})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.EqualError(t, err, `sqlite: cannot get ("foo", 3): sql: no rows in result set`)
+	require.Len(t, models, 3)
+	require.Equal(t, models[0].Value, "foo-2")
+	require.Nil(t, models[1])
+	require.Equal(t, models[2].Value, "bar-1-upsert")
+
+	results, err := repo.QueryMap(ctx, "SELECT * FROM TestComposite WHERE TenantID = ?", "foo")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, results[Key{"foo", int64(1)}.String()].Value, "foo-1")
+
+	require.NoError(t, repo.UpdateFields(ctx, Key{"foo", 1}, map[string]any{"Value": "foo-1-updated"}))
+	model, err = repo.Get(ctx, Key{"foo", 1})
+	require.NoError(t, err)
+	require.Equal(t, model.Value, "foo-1-updated")
+	require.Error(t, repo.UpdateFields(ctx, Key{"foo", 1}, map[string]any{"ID": 5}))
+
+	_, err = repo.Get(ctx, "foo")
+	require.EqualError(t, err, "invalid key type string for composite primary key of table TestComposite")
+	_, err = repo.Get(ctx, Key{"foo"})
+	require.EqualError(t, err, `invalid key ("foo") with 1 values for composite primary key of table TestComposite`)
+
+	require.NoError(t, repo.DeleteKey(ctx, Key{"foo", 1}))
+	n, err := repo.DeleteMulti(ctx, []Key{{"foo", 2}, {"bar", 1}, {"bar", 2}})
+	require.NoError(t, err)
+	require.EqualValues(t, n, 2)
+
+	n, err = repo.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 0)
+}
+
+func TestGenericCompositeKeysListPage(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testComposite]{
+		Table:       "TestComposite",
+		PrimaryKeys: []string{"TenantID", "ID"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.PutMulti(ctx, []*testComposite{
+		{TenantID: "foo", ID: 2, Value: "foo-2"},
+		{TenantID: "bar", ID: 1, Value: "bar-1"},
+		{TenantID: "foo", ID: 1, Value: "foo-1"},
+	}))
+
+	models, token, err := repo.ListPage(ctx, "", 2)
+	require.NoError(t, err)
+	require.Len(t, models, 2)
+	require.Equal(t, models[0].Value, "bar-1")
+	require.Equal(t, models[1].Value, "foo-1")
+
+	models, token, err = repo.ListPage(ctx, token, 2)
+	require.NoError(t, err)
+	require.Empty(t, token)
+	require.Len(t, models, 1)
+	require.Equal(t, models[0].Value, "foo-2")
+}
+
+func TestGenericCompositeKeysConfig(t *testing.T) {
+	db := connectDB(t)
+	defer db.Close()
+
+	_, err := NewRepoGeneric(db, RepoConfig[testComposite]{
+		Table:       "TestComposite",
+		PrimaryKeys: []string{"ID", "TenantID"},
+	})
+	require.EqualError(t, err, `invalid repository config: table TestComposite configures primary key "ID,TenantID" but model sqlite.testComposite tags "TenantID,ID"`)
+
+	_, err = NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:       "TestModels",
+		PrimaryKey:  "Name",
+		PrimaryKeys: []string{"Name", "Value"},
+	})
+	require.EqualError(t, err, `invalid repository config: table TestModels configures both PrimaryKey and PrimaryKeys`)
+
+	_, err = NewRepoGeneric(db, RepoConfig[testComposite]{
+		Table:         "TestComposite",
+		AutoIncrement: true,
+	})
+	require.EqualError(t, err, `invalid repository config: table TestComposite cannot use auto increment with a composite primary key`)
+}
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"reflect"
 	"strings"
 
 	"github.com/jmoiron/sqlx"
@@ -17,28 +16,43 @@ type RepoSingleton[T any] struct {
 	cnf RepoConfig[T]
 }
 
-func NewRepoSingleton[T any](db *sqlx.DB, cnf RepoConfig[T]) *RepoSingleton[T] {
-	cnf.fillDefaults()
+// NewRepoSingleton creates a repository for models stored in a table where each key has a single
+// row. It returns an error if the configuration does not match the fields of the model.
+func NewRepoSingleton[T any](db *sqlx.DB, cnf RepoConfig[T]) (*RepoSingleton[T], error) {
+	if err := cnf.prepare(db); err != nil {
+		return nil, fmt.Errorf("invalid repository config: %w", err)
+	}
 	return &RepoSingleton[T]{
 		db:  db,
 		cnf: cnf,
-	}
+	}, nil
 }
 
 func (repo *RepoSingleton[T]) conn() *sqlx.DB {
 	return repo.db
 }
 
-func (repo *RepoSingleton[T]) getPK(model *T) (reflect.Value, any) {
-	v := reflect.ValueOf(model).Elem()
-	f := v.FieldByName(repo.cnf.PrimaryKey)
-	return f, f.Interface()
-}
-
 func (repo *RepoSingleton[T]) BeginTx(ctx context.Context) (*Tx[T], error) {
 	return newTx(ctx, repo.db, repo.cnf)
 }
 
+// WithTx returns a view of the shared transaction to operate with the models of this repository.
+func (repo *RepoSingleton[T]) WithTx(tx *SharedTx) *Tx[T] {
+	return &Tx[T]{
+		shared: tx,
+		cnf:    repo.cnf,
+	}
+}
+
+// RunInTx runs fn inside a transaction that is committed if it returns nil and rolled back otherwise.
+// It is retried when the database is busy. See the package level RunInTx for details.
+func (repo *RepoSingleton[T]) RunInTx(ctx context.Context, fn func(tx *Tx[T]) error, options ...TxOption) error {
+	begin := func() (*Tx[T], error) {
+		return repo.BeginTx(ctx)
+	}
+	return runInTx(ctx, begin, fn, options)
+}
+
 func (repo *RepoSingleton[T]) Put(ctx context.Context, model *T) error {
 	tx, err := repo.BeginTx(ctx)
 	if err != nil {
@@ -54,68 +68,72 @@ func (repo *RepoSingleton[T]) Put(ctx context.Context, model *T) error {
 	return nil
 }
 
-func (repo *RepoSingleton[T]) Get(ctx context.Context, key string) (*T, error) {
-	if key == "" {
+func (repo *RepoSingleton[T]) Get(ctx context.Context, key any) (*T, error) {
+	key, err := repo.store().keyValue(key)
+	if err != nil {
+		return nil, err
+	}
+	if repo.store().isEmptyKey(key) {
 		return nil, fmt.Errorf("empty key: %w", sql.ErrNoRows)
 	}
 
+	conds, args, err := repo.store().scope(ctx, []string{repo.store().pkWhere()}, repo.store().keyArgs(key))
+	if err != nil {
+		return nil, err
+	}
+
 	var model T
 	cols, _ := listCols(repo.db, model)
-	q := fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?", strings.Join(cols, ","), repo.cnf.Table, repo.cnf.PrimaryKey)
-	repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoSingleton.Get"), slog.String("q", q), slog.String("key", key))
-	if err := repo.db.GetContext(ctx, &model, q, key); err != nil {
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(cols, ","), repo.cnf.Table, strings.Join(conds, " AND "))
+	repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoSingleton.Get"), slog.String("q", q), slog.Any("key", key))
+	if err := repo.db.GetContext(ctx, &model, q, args...); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			rv, _ := repo.getPK(&model)
-			rv.Set(reflect.ValueOf(key))
+			repo.store().setKey(&model, key)
 			return &model, nil
 		}
 		return nil, fmt.Errorf("cannot execute query: %w", err)
 	}
+	if err := runAfterLoad(ctx, repo.cnf.Hooks, &model); err != nil {
+		return nil, err
+	}
 	return &model, nil
 }
 
-func (repo *RepoSingleton[T]) Exists(ctx context.Context, key string) (bool, error) {
-	if key == "" {
-		return false, nil
-	}
+func (repo *RepoSingleton[T]) store() store[T] {
+	return store[T]{db: repo.db, ext: repo.db, cnf: repo.cnf, prefix: "RepoSingleton"}
+}
 
-	var count int64
-	q := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ?", repo.cnf.Table, repo.cnf.PrimaryKey)
-	repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoSingleton.Exists"), slog.String("q", q), slog.String("key", key))
-	if err := repo.db.GetContext(ctx, &count, q, key); err != nil {
-		return false, fmt.Errorf("cannot execute query: %w", err)
-	}
-	return count > 0, nil
+func (repo *RepoSingleton[T]) Exists(ctx context.Context, key any) (bool, error) {
+	return repo.store().exists(ctx, key)
 }
 
 func (repo *RepoSingleton[T]) Query(ctx context.Context, query string, args ...interface{}) (*T, error) {
-	query = normalizeQuery(query)
-	var model T
-	repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoSingleton.Query"), slog.String("q", query))
-	if err := repo.db.GetContext(ctx, &model, query, args...); err != nil {
-		return nil, fmt.Errorf("cannot execute query: %w", err)
-	}
-	return &model, nil
+	return repo.store().query(ctx, query, args...)
 }
 
 func (repo *RepoSingleton[T]) QueryList(ctx context.Context, query string, args ...interface{}) ([]*T, error) {
-	query = normalizeQuery(query)
-	repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoSingleton.QueryList"), slog.String("q", query))
-	var models []*T
-	if err := repo.db.SelectContext(ctx, &models, query, args...); err != nil {
-		return nil, fmt.Errorf("cannot execute query: %w", err)
-	}
-	return models, nil
+	return repo.store().queryList(ctx, query, args...)
 }
 
 func (repo *RepoSingleton[T]) List(ctx context.Context) ([]*T, error) {
-	var models []*T
+	return repo.store().list(ctx)
+}
+
+// QueryIter streams the results of the query one row at a time instead of loading them all in memory.
+func (repo *RepoSingleton[T]) QueryIter(ctx context.Context, query string, args ...interface{}) Seq[T] {
+	return newSeq[T](ctx, repo.db, repo.cnf, "RepoSingleton.QueryIter", normalizeQuery(query), args)
+}
+
+// ListIter streams all the rows of the table one at a time instead of loading them all in memory.
+func (repo *RepoSingleton[T]) ListIter(ctx context.Context) Seq[T] {
 	var single T
 	cols, _ := listCols(repo.db, single)
-	q := fmt.Sprintf("SELECT %s FROM %s", strings.Join(cols, ","), repo.cnf.Table)
-	repo.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "RepoSingleton.List"), slog.String("q", q))
-	if err := repo.db.SelectContext(ctx, &models, q); err != nil {
-		return nil, fmt.Errorf("cannot execute query: %w", err)
+	where, args, err := repo.store().where(ctx, nil, nil)
+	if err != nil {
+		return func(yield func(*T, error) bool) {
+			yield(nil, err)
+		}
 	}
-	return models, nil
+	q := fmt.Sprintf("SELECT %s FROM %s%s", strings.Join(cols, ","), repo.cnf.Table, where)
+	return newSeq[T](ctx, repo.db, repo.cnf, "RepoSingleton.ListIter", q, args)
 }
@@ -13,10 +13,11 @@ func TestSingletonPutSingleton(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoSingleton(db, RepoConfig[testModel]{
+	repo, err := NewRepoSingleton(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	foo := &testModel{
 		Name:  "foo-name",
@@ -35,12 +36,13 @@ func TestSingletonGet(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoSingleton(db, RepoConfig[testModel]{
+	repo, err := NewRepoSingleton(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
-	_, err := db.Exec("INSERT INTO TestModels (Name, Value) VALUES (?, ?)", "foo-name", "foo-value")
+	_, err = db.Exec("INSERT INTO TestModels (Name, Value) VALUES (?, ?)", "foo-name", "foo-value")
 	require.NoError(t, err)
 
 	other, err := repo.Get(ctx, "foo-name")
@@ -54,10 +56,11 @@ func TestSingletonGetNotFound(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoSingleton(db, RepoConfig[testModel]{
+	repo, err := NewRepoSingleton(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	other, err := repo.Get(ctx, "foo-name")
 	require.NoError(t, err)
@@ -70,10 +73,11 @@ func TestSingletonGetEmptyKeyNotFound(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoSingleton(db, RepoConfig[testModel]{
+	repo, err := NewRepoSingleton(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	other, err := repo.Get(ctx, "")
 	require.Nil(t, other)
@@ -86,12 +90,13 @@ func TestSingletonExists(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoSingleton(db, RepoConfig[testModel]{
+	repo, err := NewRepoSingleton(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
-	_, err := db.Exec("INSERT INTO TestModels (Name, Value) VALUES (?, ?)", "foo-name", "foo-value")
+	_, err = db.Exec("INSERT INTO TestModels (Name, Value) VALUES (?, ?)", "foo-name", "foo-value")
 	require.NoError(t, err)
 
 	exists, err := repo.Exists(ctx, "foo-name")
@@ -108,10 +113,11 @@ func TestSingletonExistsShortcircuits(t *testing.T) {
 	db := connectDB(t)
 	defer db.Close()
 
-	repo := NewRepoSingleton(db, RepoConfig[testModel]{
+	repo, err := NewRepoSingleton(db, RepoConfig[testModel]{
 		Table:      "TestModels",
 		PrimaryKey: "Name",
 	})
+	require.NoError(t, err)
 
 	require.NoError(t, repo.Put(ctx, &testModel{Name: "", Value: "foo-value"}))
 
@@ -119,3 +125,26 @@ func TestSingletonExistsShortcircuits(t *testing.T) {
 	require.NoError(t, err)
 	require.False(t, exists)
 }
+
+func TestSingletonListIter(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoSingleton(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+
+	var models []*testModel
+	repo.ListIter(ctx)(func(model *testModel, err error) bool {
+		require.NoError(t, err)
+		models = append(models, model)
+		return true
+	})
+	require.Len(t, models, 1)
+	require.Equal(t, models[0].Value, "foo-value")
+}
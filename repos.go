@@ -1,35 +1,301 @@
 package sqlite
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/mattn/go-sqlite3"
 )
 
+// RepoConfig configures a repository. The columns are referenced by their name in the
+// database, which is the name in the sqlite struct tag of the field or the name of the field
+// if there is no tag.
 type RepoConfig[T any] struct {
-	Table      string
+	// Table is the name of the table, optionally qualified with the schema like main.Users. Quote
+	// it to use reserved words or other special names, like `"Order"`.
+	Table string
+
+	// PrimaryKey is the column of the primary key. If empty, the field with the pk option in
+	// its struct tag is used. The key can be a string, an integer or any other comparable type
+	// like a UUID. Keys passed to the repository are converted to the type of the field.
 	PrimaryKey string
-	Hooks      Hooks[T]
-	Logger     *slog.Logger
+
+	// PrimaryKeys are the columns of a composite primary key, used instead of PrimaryKey. Keys
+	// of these repositories are passed as a Key with the values in the same order. If empty,
+	// the fields with the pk option in their struct tags are used.
+	PrimaryKeys []string
+
+	Hooks  Hooks[T]
+	Logger *slog.Logger
+
+	// PageOrder is the column used to sort the results of ListPage. The primary key is
+	// always appended to break ties. By default it sorts by the primary key only.
+	PageOrder string
+
+	// Version is an optional integer column used for optimistic concurrency control. When set,
+	// Put inserts models with a zero version and updates the rest only if the stored version
+	// matches the model. Otherwise it fails with a ConflictError. The version is incremented on
+	// every successful write.
+	Version string
+
+	// CreatedAt and UpdatedAt are optional time.Time columns filled automatically when writing
+	// models. CreatedAt keeps the value already stored in the database when updating a row.
+	CreatedAt string
+	UpdatedAt string
+
+	// SoftDelete is an optional nullable time column. When set, deletes store the current time
+	// in it instead of removing the rows, and reads through the repository ignore the rows where
+	// it is not NULL. Use WithDeleted to read them, Restore to undelete them and Purge or
+	// PurgeDeleted to remove them permanently.
+	SoftDelete string
+
+	// Tenant is an optional column that scopes the repository to the rows of a single tenant.
+	// The tenant is bound with ForTenant or taken from the context assigned with WithTenant, and
+	// operations fail with ErrMissingTenant if there is none. Reads and deletes only see the rows
+	// of the tenant, and writes assign it to the models or fail with a TenantError if they
+	// belong to another one. Raw SQL run with Query, QueryList or Exec is not scoped.
+	Tenant string
+
+	// AutoIncrement lets SQLite assign the integer primary key of the new rows. Models with a
+	// zero key are written without it and the assigned key is stored back in the model.
+	AutoIncrement bool
+
+	// Audit is the optional name of a table where every write and delete of the repository is
+	// recorded, in the same transaction as the change. Create it with AuditMigration. Use
+	// WithActor to record the author of the changes.
+	Audit string
+
+	// Clock returns the current time for the automatic timestamps and soft deletes. By default
+	// it is time.Now.
+	Clock func() time.Time
 }
 
 func (c *RepoConfig[T]) fillDefaults() {
 	if c.Logger == nil {
 		c.Logger = slog.Default()
 	}
+	if c.Clock == nil {
+		c.Clock = time.Now
+	}
+}
+
+var identifierRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// tableRe matches the table names that can be used in the generated statements: plain or quoted
+// identifiers with an optional schema.
+var tableRe = func() *regexp.Regexp {
+	name := `(?:[A-Za-z_][A-Za-z0-9_]*|"(?:[^"]|"")+"|` + "`(?:[^`]|``)+`" + `|\[[^\]]+\])`
+	return regexp.MustCompile(`^(?:` + name + `\.)?` + name + `$`)
+}()
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	nullTimeType = reflect.TypeOf(sql.NullTime{})
+)
+
+// prepare fills the default values of the configuration and checks it against the fields of
+// the model, so mistakes are reported when creating the repository instead of when using it.
+func (c *RepoConfig[T]) prepare(db *sqlx.DB) error {
+	c.fillDefaults()
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("model %s must be a struct", t)
+	}
+	if !tableRe.MatchString(c.Table) {
+		return fmt.Errorf("invalid table name %q for model %s", c.Table, t)
+	}
+
+	fields := make(map[string]*reflectx.FieldInfo)
+	var tagged []string
+	for _, fi := range modelFields(db, t) {
+		fields[fi.Path] = fi
+		if err := checkLegacyTag(t, fi); err != nil {
+			return err
+		}
+		if _, ok := fi.Options[tagPrimaryKey]; ok {
+			tagged = append(tagged, fi.Path)
+		}
+	}
+
+	if c.PrimaryKey != "" && len(c.PrimaryKeys) > 0 {
+		return fmt.Errorf("table %s configures both PrimaryKey and PrimaryKeys", c.Table)
+	}
+	if c.PrimaryKey != "" {
+		c.PrimaryKeys = []string{c.PrimaryKey}
+	}
+	switch {
+	case len(c.PrimaryKeys) == 0 && len(tagged) > 0:
+		c.PrimaryKeys = tagged
+	case len(c.PrimaryKeys) == 0:
+		return fmt.Errorf("table %s has no primary key configured", c.Table)
+	case len(tagged) > 0 && strings.Join(tagged, ",") != strings.Join(c.PrimaryKeys, ","):
+		return fmt.Errorf("table %s configures primary key %q but model %s tags %q", c.Table, strings.Join(c.PrimaryKeys, ","), t, strings.Join(tagged, ","))
+	}
+	c.PrimaryKeys = append([]string(nil), c.PrimaryKeys...)
+	if len(c.PrimaryKeys) == 1 {
+		c.PrimaryKey = c.PrimaryKeys[0]
+	}
+
+	check := func(option, col, expected string, valid func(t reflect.Type) bool) error {
+		if col == "" {
+			return nil
+		}
+		fi, ok := fields[col]
+		if !ok {
+			return fmt.Errorf("%s column %q of table %s is not a field of model %s", option, col, c.Table, t)
+		}
+		if valid != nil && !valid(fi.Field.Type) {
+			return fmt.Errorf("%s column %q of table %s must be %s, not %s", option, col, c.Table, expected, fi.Field.Type)
+		}
+		return nil
+	}
+	isKey := func(t reflect.Type) bool {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface:
+			return false
+		}
+		return t.Comparable()
+	}
+	isInt := func(t reflect.Type) bool {
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return true
+		}
+		return false
+	}
+	isTime := func(t reflect.Type) bool {
+		return t == timeType
+	}
+	isNullTime := func(t reflect.Type) bool {
+		return t == reflect.PointerTo(timeType) || t == nullTimeType
+	}
+	var checks []error
+	for _, col := range c.PrimaryKeys {
+		checks = append(checks, check("primary key", col, "a comparable value", isKey))
+	}
+	checks = append(checks,
+		check("page order", c.PageOrder, "", nil),
+		check("version", c.Version, "an integer", isInt),
+		check("created at", c.CreatedAt, "a time.Time", isTime),
+		check("updated at", c.UpdatedAt, "a time.Time", isTime),
+		check("soft delete", c.SoftDelete, "a *time.Time or sql.NullTime", isNullTime),
+		check("tenant", c.Tenant, "a comparable value", isKey),
+	)
+	for _, err := range checks {
+		if err != nil {
+			return err
+		}
+	}
+	if c.AutoIncrement {
+		if len(c.PrimaryKeys) > 1 {
+			return fmt.Errorf("table %s cannot use auto increment with a composite primary key", c.Table)
+		}
+		if err := check("auto increment primary key", c.PrimaryKey, "an integer", isInt); err != nil {
+			return err
+		}
+	}
+
+	if c.Audit != "" && !identifierRe.MatchString(c.Audit) {
+		return fmt.Errorf("invalid audit table name %q for table %s", c.Audit, c.Table)
+	}
+
+	return nil
+}
+
+// Options of the sqlite struct tag that can follow the column name, for example
+// `sqlite:"created_at,readonly"`.
+const (
+	// tagPrimaryKey marks the primary key columns when they are not configured in RepoConfig.
+	tagPrimaryKey = "pk"
+
+	// tagReadOnly marks a column that is read from the database but never written, like
+	// generated columns or values assigned by triggers.
+	tagReadOnly = "readonly"
+
+	// tagOmitEmpty skips the column when writing a model where it has the zero value, so the
+	// default value of the column is used instead.
+	tagOmitEmpty = "omitempty"
+)
+
+// checkLegacyTag rejects the fields that are mapped differently by the db struct tag used
+// before, so models are not silently written to the wrong columns.
+func checkLegacyTag(t reflect.Type, fi *reflectx.FieldInfo) error {
+	legacy, ok := fi.Field.Tag.Lookup("db")
+	if !ok {
+		return nil
+	}
+	if _, ok := fi.Field.Tag.Lookup("sqlite"); ok {
+		return nil
+	}
+	if name, _, _ := strings.Cut(legacy, ","); name != fi.Name {
+		return fmt.Errorf("field %s of model %s has a db struct tag, use the sqlite tag instead: `sqlite:%q`", fi.Field.Name, t, legacy)
+	}
+	return nil
+}
+
+// modelFields returns the mapped fields of the model type in the order of the struct fields,
+// so the generated statements are stable and can be reused.
+func modelFields(db *sqlx.DB, t reflect.Type) []*reflectx.FieldInfo {
+	tm := db.Mapper.TypeMap(t)
+	var fields []*reflectx.FieldInfo
+	for _, fi := range tm.Index {
+		if tm.Names[fi.Path] != fi {
+			continue
+		}
+		fields = append(fields, fi)
+	}
+	return fields
 }
 
+// listCols returns the columns of the model and their values.
 func listCols(db *sqlx.DB, model any) ([]string, []any) {
+	v := reflect.Indirect(reflect.ValueOf(model))
 	var keys []string
 	var values []any
-	for key, value := range db.Mapper.FieldMap(reflect.ValueOf(model)) {
-		keys = append(keys, key)
-		values = append(values, value.Interface())
+	for _, fi := range modelFields(db, v.Type()) {
+		keys = append(keys, fi.Path)
+		values = append(values, reflectx.FieldByIndexesReadOnly(v, fi.Index).Interface())
+	}
+	return keys, values
+}
+
+// hasReadOnlyCols reports if the model type has columns that are never written.
+func hasReadOnlyCols(db *sqlx.DB, t reflect.Type) bool {
+	for _, fi := range modelFields(db, t) {
+		if _, ok := fi.Options[tagReadOnly]; ok {
+			return true
+		}
 	}
+	return false
+}
 
+// listWriteCols returns the columns of the model and their values that should be written to
+// the database, skipping the read only columns and the empty ones that can be omitted.
+func listWriteCols(db *sqlx.DB, model any) ([]string, []any) {
+	v := reflect.Indirect(reflect.ValueOf(model))
+	var keys []string
+	var values []any
+	for _, fi := range modelFields(db, v.Type()) {
+		if _, ok := fi.Options[tagReadOnly]; ok {
+			continue
+		}
+		field := reflectx.FieldByIndexesReadOnly(v, fi.Index)
+		if _, ok := fi.Options[tagOmitEmpty]; ok && field.IsZero() {
+			continue
+		}
+		keys = append(keys, fi.Path)
+		values = append(values, field.Interface())
+	}
 	return keys, values
 }
 
@@ -41,10 +307,59 @@ func normalizeQuery(q string) string {
 	return strings.TrimSpace(strings.Join(lines, " "))
 }
 
+// formatKey prints a primary key for the error messages, quoting it if it is a string.
+func formatKey(key any) string {
+	if v := reflect.ValueOf(key); v.Kind() == reflect.String {
+		return strconv.Quote(v.String())
+	}
+	return fmt.Sprint(key)
+}
+
 type MissingKeyError struct {
-	Key string
+	Key any
 }
 
 func (e MissingKeyError) Error() string {
-	return fmt.Sprintf("sqlite: cannot get %q", e.Key)
+	return fmt.Sprintf("sqlite: cannot get %s", formatKey(e.Key))
+}
+
+// PutError is reported for each model that cannot be stored in a batch operation.
+type PutError struct {
+	Index int
+	Key   any
+	Err   error
+}
+
+func (e PutError) Error() string {
+	return fmt.Sprintf("sqlite: cannot put model %d %s: %s", e.Index, formatKey(e.Key), e.Err)
+}
+
+func (e PutError) Unwrap() error {
+	return e.Err
+}
+
+// ConflictError is returned when a model cannot be written because it was modified concurrently.
+type ConflictError struct {
+	Key any
+}
+
+func (e ConflictError) Error() string {
+	return fmt.Sprintf("sqlite: version conflict for %s", formatKey(e.Key))
+}
+
+// AlreadyExistsError is returned when inserting a model whose key is already in use.
+type AlreadyExistsError struct {
+	Key any
+}
+
+func (e AlreadyExistsError) Error() string {
+	return fmt.Sprintf("sqlite: %s already exists", formatKey(e.Key))
+}
+
+func isPrimaryKeyConflict(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	return false
 }
@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+type TxOption func(opts *txOptions)
+
+type txOptions struct {
+	attempts int
+	backoff  time.Duration
+}
+
+// WithTxAttempts configures the maximum number of times the transaction will be tried when the
+// database is busy. By default it is tried 5 times.
+func WithTxAttempts(attempts int) TxOption {
+	return func(opts *txOptions) {
+		opts.attempts = attempts
+	}
+}
+
+// WithTxBackoff configures the wait before the first retry. It doubles after each attempt.
+// By default it starts at 10ms.
+func WithTxBackoff(backoff time.Duration) TxOption {
+	return func(opts *txOptions) {
+		opts.backoff = backoff
+	}
+}
+
+type finisher interface {
+	Commit() error
+	Rollback() error
+}
+
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
+
+func runInTx[X finisher](ctx context.Context, begin func() (X, error), fn func(tx X) error, options []TxOption) error {
+	opts := txOptions{
+		attempts: 5,
+		backoff:  10 * time.Millisecond,
+	}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	backoff := opts.backoff
+	for attempt := 1; ; attempt++ {
+		err := runTxAttempt(begin, fn)
+		if err == nil || !isBusy(err) || attempt >= opts.attempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func runTxAttempt[X finisher](begin func() (X, error), fn func(tx X) error) error {
+	tx, err := begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			_ = tx.Rollback()
+			panic(rec)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("cannot commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RunInTx runs fn inside a database transaction. The transaction is committed if fn returns nil
+// and rolled back if it returns an error or panics. The whole function is retried with an exponential
+// backoff when the database is busy or locked by other writers.
+//
+// Repositories can join the transaction with their WithTx method to commit their changes together.
+func RunInTx(ctx context.Context, db *sqlx.DB, fn func(tx *SharedTx) error, options ...TxOption) error {
+	begin := func() (*SharedTx, error) {
+		return BeginTx(ctx, db)
+	}
+	return runInTx(ctx, begin, fn, options)
+}
@@ -0,0 +1,129 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunInTxCommit(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.RunInTx(ctx, func(tx *Tx[testModel]) error {
+		return tx.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"})
+	}))
+
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, count, 1)
+}
+
+func TestRunInTxRollback(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	err = repo.RunInTx(ctx, func(tx *Tx[testModel]) error {
+		require.NoError(t, tx.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+		return errors.New("failed")
+	})
+	require.EqualError(t, err, "failed")
+
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, count, 0)
+}
+
+func TestRunInTxRollbackPanic(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	require.PanicsWithValue(t, "boom", func() {
+		_ = repo.RunInTx(ctx, func(tx *Tx[testModel]) error {
+			require.NoError(t, tx.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+			panic("boom")
+		})
+	})
+
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, count, 0)
+}
+
+func TestRunInTxRetryBusy(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	var calls int
+	err := RunInTx(ctx, db, func(tx *SharedTx) error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("cannot execute query: %w", sqlite3.Error{Code: sqlite3.ErrBusy})
+		}
+		_, err := tx.ExecContext(ctx, "INSERT INTO TestModels (Name, Value) VALUES (?, ?)", "foo-name", "foo-value")
+		return err
+	}, WithTxBackoff(time.Millisecond))
+	require.NoError(t, err)
+	require.Equal(t, calls, 3)
+
+	var count int64
+	require.NoError(t, db.Get(&count, "SELECT COUNT(*) FROM TestModels"))
+	require.EqualValues(t, count, 1)
+}
+
+func TestRunInTxRetryAttempts(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	var calls int
+	err := RunInTx(ctx, db, func(tx *SharedTx) error {
+		calls++
+		return sqlite3.Error{Code: sqlite3.ErrLocked}
+	}, WithTxAttempts(2), WithTxBackoff(time.Millisecond))
+	var sqliteErr sqlite3.Error
+	require.ErrorAs(t, err, &sqliteErr)
+	require.Equal(t, sqliteErr.Code, sqlite3.ErrLocked)
+	require.Equal(t, calls, 2)
+}
+
+func TestRunInTxNoRetryOtherErrors(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	var calls int
+	err := RunInTx(ctx, db, func(tx *SharedTx) error {
+		calls++
+		return errors.New("failed")
+	})
+	require.EqualError(t, err, "failed")
+	require.Equal(t, calls, 1)
+}
@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SharedTx is a database transaction that is not bound to any model. Several repositories
+// can operate inside it through their WithTx method, so all of their changes are committed
+// or rolled back together.
+type SharedTx struct {
+	*sqlx.Tx
+	db          *sqlx.DB
+	stmts       map[string]*sqlx.Stmt
+	afterCommit []func()
+	changes     []ChangeEvent
+}
+
+// BeginTx opens a new transaction that can be shared by several repositories.
+func BeginTx(ctx context.Context, db *sqlx.DB) (*SharedTx, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot begin transaction: %w", err)
+	}
+
+	return &SharedTx{
+		Tx:    tx,
+		db:    db,
+		stmts: make(map[string]*sqlx.Stmt),
+	}, nil
+}
+
+// execPrepared runs the statement reusing a prepared version of it if it was already used
+// inside this transaction. Prepared statements are released when the transaction finishes.
+func (tx *SharedTx) execPrepared(ctx context.Context, q string, args ...any) (sql.Result, error) {
+	stmt, ok := tx.stmts[q]
+	if !ok {
+		var err error
+		stmt, err = tx.PreparexContext(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		tx.stmts[q] = stmt
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+// AfterCommit registers fn to run once the transaction has been committed successfully.
+// The callbacks run in registration order and are discarded if the transaction is rolled back.
+func (tx *SharedTx) AfterCommit(fn func()) {
+	tx.afterCommit = append(tx.afterCommit, fn)
+}
+
+// recordChange queues the event to be published to the subscriptions after the commit.
+func (tx *SharedTx) recordChange(event ChangeEvent) {
+	tx.changes = append(tx.changes, event)
+}
+
+// Commit commits the transaction, publishes the changes to the subscriptions and then runs
+// the AfterCommit callbacks.
+func (tx *SharedTx) Commit() error {
+	changes := tx.changes
+	tx.changes = nil
+	if err := tx.Tx.Commit(); err != nil {
+		tx.afterCommit = nil
+		return err
+	}
+	publishChanges(tx.db, changes)
+
+	callbacks := tx.afterCommit
+	tx.afterCommit = nil
+	for _, fn := range callbacks {
+		fn()
+	}
+	return nil
+}
+
+// Rollback aborts the transaction and discards the AfterCommit callbacks and the changes.
+func (tx *SharedTx) Rollback() error {
+	tx.afterCommit = nil
+	tx.changes = nil
+	return tx.Tx.Rollback()
+}
@@ -0,0 +1,228 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// store runs the read and write operations shared by the repositories and transactions. The
+// statements are executed directly in the database or inside a transaction depending on ext.
+type store[T any] struct {
+	db          *sqlx.DB
+	ext         sqlx.ExtContext
+	cnf         RepoConfig[T]
+	prefix      string
+	withDeleted bool
+	tenant      any
+}
+
+func (s store[T]) log(ctx context.Context, method, q string, attrs ...slog.Attr) {
+	args := []any{slog.String("method", s.prefix+"."+method), slog.String("q", q)}
+	for _, attr := range attrs {
+		args = append(args, attr)
+	}
+	s.cnf.Logger.Log(ctx, levelTrace, "SQL", args...)
+}
+
+// where builds the WHERE clause joining the conditions with the filters applied by default to
+// every read of the repository, like excluding the soft deleted rows or the rows of other
+// tenants. It returns the arguments of the conditions followed by the ones of the filters.
+func (s store[T]) where(ctx context.Context, conds []string, args []any) (string, []any, error) {
+	conds, args, err := s.scope(ctx, conds, args)
+	if err != nil {
+		return "", nil, err
+	}
+	if s.cnf.SoftDelete != "" && !s.withDeleted {
+		conds = append(conds, s.cnf.SoftDelete+" IS NULL")
+	}
+	if len(conds) == 0 {
+		return "", args, nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args, nil
+}
+
+func (s store[T]) selectCols() string {
+	var single T
+	cols, _ := listCols(s.db, single)
+	return strings.Join(cols, ",")
+}
+
+func (s store[T]) count(ctx context.Context) (int64, error) {
+	where, args, err := s.where(ctx, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	q := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", s.cnf.Table, where)
+	s.log(ctx, "Count", q)
+	if err := sqlx.GetContext(ctx, s.ext, &count, q, args...); err != nil {
+		return 0, fmt.Errorf("cannot execute query: %w", err)
+	}
+	return count, nil
+}
+
+func (s store[T]) list(ctx context.Context) ([]*T, error) {
+	where, args, err := s.where(ctx, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var models []*T
+	q := fmt.Sprintf("SELECT %s FROM %s%s", s.selectCols(), s.cnf.Table, where)
+	s.log(ctx, "List", q)
+	if err := sqlx.SelectContext(ctx, s.ext, &models, q, args...); err != nil {
+		return nil, fmt.Errorf("cannot execute query: %w", err)
+	}
+	if err := runAfterLoad(ctx, s.cnf.Hooks, models...); err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+func (s store[T]) get(ctx context.Context, key any) (*T, error) {
+	key, err := s.keyValue(key)
+	if err != nil {
+		return nil, err
+	}
+	if s.isEmptyKey(key) {
+		return nil, fmt.Errorf("empty key: %w", sql.ErrNoRows)
+	}
+
+	where, args, err := s.where(ctx, []string{s.pkWhere()}, s.keyArgs(key))
+	if err != nil {
+		return nil, err
+	}
+	var model T
+	q := fmt.Sprintf("SELECT %s FROM %s%s", s.selectCols(), s.cnf.Table, where)
+	s.log(ctx, "Get", q, slog.Any("key", key))
+	if err := sqlx.GetContext(ctx, s.ext, &model, q, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %w", &MissingKeyError{key}, err)
+		}
+		return nil, fmt.Errorf("cannot execute query: %w", err)
+	}
+	if err := runAfterLoad(ctx, s.cnf.Hooks, &model); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// getMulti returns the models of the keys in the same order. Keys must be a slice of values
+// that can be converted to the type of the primary key.
+func (s store[T]) getMulti(ctx context.Context, keys any) ([]*T, error) {
+	values, err := s.keyValues(keys)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	in, args := s.pkIn(values)
+	where, args, err := s.where(ctx, []string{in}, args)
+	if err != nil {
+		return nil, err
+	}
+	q := fmt.Sprintf("SELECT %s FROM %s%s", s.selectCols(), s.cnf.Table, where)
+	models, err := s.queryMap(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var multi []error
+	var results []*T
+	for _, key := range values {
+		if model := models[s.mapKey(key)]; model != nil {
+			results = append(results, model)
+		} else {
+			multi = append(multi, fmt.Errorf("%w: %w", &MissingKeyError{key}, sql.ErrNoRows))
+			results = append(results, nil)
+		}
+	}
+
+	if err := errors.Join(multi...); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+func (s store[T]) exists(ctx context.Context, key any) (bool, error) {
+	key, err := s.keyValue(key)
+	if err != nil {
+		return false, err
+	}
+	if s.isEmptyKey(key) {
+		return false, nil
+	}
+
+	where, args, err := s.where(ctx, []string{s.pkWhere()}, s.keyArgs(key))
+	if err != nil {
+		return false, err
+	}
+	q := fmt.Sprintf("SELECT COUNT(*) FROM %s%s", s.cnf.Table, where)
+	s.log(ctx, "Exists", q, slog.Any("key", key))
+	var count int64
+	if err := sqlx.GetContext(ctx, s.ext, &count, q, args...); err != nil {
+		return false, fmt.Errorf("cannot execute query: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (s store[T]) query(ctx context.Context, query string, args ...interface{}) (*T, error) {
+	query = normalizeQuery(query)
+	s.log(ctx, "Query", query)
+	var model T
+	if err := sqlx.GetContext(ctx, s.ext, &model, query, args...); err != nil {
+		return nil, fmt.Errorf("cannot execute query: %w", err)
+	}
+	if err := runAfterLoad(ctx, s.cnf.Hooks, &model); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+func (s store[T]) queryList(ctx context.Context, query string, args ...interface{}) ([]*T, error) {
+	query = normalizeQuery(query)
+	s.log(ctx, "QueryList", query)
+	var models []*T
+	if err := sqlx.SelectContext(ctx, s.ext, &models, query, args...); err != nil {
+		return nil, fmt.Errorf("cannot execute query: %w", err)
+	}
+	if err := runAfterLoad(ctx, s.cnf.Hooks, models...); err != nil {
+		return nil, err
+	}
+	return models, nil
+}
+
+func (s store[T]) queryMap(ctx context.Context, query string, args ...interface{}) (map[any]*T, error) {
+	s.log(ctx, "QueryMap", query)
+	var models []*T
+	if err := sqlx.SelectContext(ctx, s.ext, &models, query, args...); err != nil {
+		return nil, fmt.Errorf("cannot execute query: %w", err)
+	}
+	if err := runAfterLoad(ctx, s.cnf.Hooks, models...); err != nil {
+		return nil, err
+	}
+
+	keyed := make(map[any]*T)
+	for _, m := range models {
+		keyed[s.mapKey(s.modelKey(m))] = m
+	}
+
+	return keyed, nil
+}
+
+func (s store[T]) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query = normalizeQuery(query)
+	s.log(ctx, "Exec", query)
+	result, err := s.ext.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot execute query: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+)
+
+// ErrMissingTenant is returned when operating with a repository scoped by tenant without a tenant
+// in the context or bound with ForTenant.
+var ErrMissingTenant = errors.New("sqlite: missing tenant")
+
+// TenantError is returned when writing a model that belongs to a different tenant.
+type TenantError struct {
+	Key any
+}
+
+func (e TenantError) Error() string {
+	return fmt.Sprintf("sqlite: %s belongs to another tenant", formatKey(e.Key))
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a context that restricts the operations of the repositories with a Tenant
+// column to the rows of the tenant. It also selects the database of a TenantManager.
+func WithTenant(ctx context.Context, tenant any) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// tenantValue returns the tenant the operations are restricted to, bound with ForTenant or
+// assigned to the context with WithTenant. It returns nil if the repository is not scoped.
+func (s store[T]) tenantValue(ctx context.Context) (any, error) {
+	if s.cnf.Tenant == "" {
+		return nil, nil
+	}
+	tenant := s.tenant
+	if tenant == nil {
+		tenant = ctx.Value(tenantKey{})
+	}
+	if tenant == nil {
+		return nil, fmt.Errorf("%w for table %s", ErrMissingTenant, s.cnf.Table)
+	}
+
+	tm := s.db.Mapper.TypeMap(reflect.TypeOf((*T)(nil)).Elem())
+	t := tm.GetByPath(s.cnf.Tenant).Field.Type
+	value, ok := convertValue(t, tenant)
+	if !ok {
+		return nil, fmt.Errorf("invalid tenant type %T for column %s of type %s", tenant, s.cnf.Tenant, t)
+	}
+	return value, nil
+}
+
+// scope adds the condition that restricts the rows to the tenant of the operation.
+func (s store[T]) scope(ctx context.Context, conds []string, args []any) ([]string, []any, error) {
+	conds = conds[:len(conds):len(conds)]
+	args = args[:len(args):len(args)]
+	tenant, err := s.tenantValue(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tenant != nil {
+		conds = append(conds, s.cnf.Tenant+" = ?")
+		args = append(args, tenant)
+	}
+	return conds, args, nil
+}
+
+// checkTenant assigns the tenant of the operation to the model if it has none, and verifies that
+// both the model and the row stored with the same key belong to it.
+func (tx *Tx[T]) checkTenant(ctx context.Context, model *T) error {
+	s := tx.store()
+	tenant, err := s.tenantValue(ctx)
+	if err != nil || tenant == nil {
+		return err
+	}
+
+	field := s.db.Mapper.FieldByName(reflect.ValueOf(model), tx.cnf.Tenant)
+	if field.IsZero() {
+		field.Set(reflect.ValueOf(tenant))
+	} else if field.Interface() != tenant {
+		return &TenantError{tx.key(model)}
+	}
+
+	// The key already separates the rows of each tenant.
+	if slices.Contains(tx.cnf.PrimaryKeys, tx.cnf.Tenant) {
+		return nil
+	}
+
+	stored := reflect.New(field.Type())
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE %s", tx.cnf.Tenant, tx.cnf.Table, s.pkWhere())
+	tx.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "Tx.checkTenant"), slog.String("q", q))
+	if err := tx.shared.GetContext(ctx, stored.Interface(), q, s.keyArgs(tx.key(model))...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("cannot execute query: %w", err)
+	}
+	if stored.Elem().Interface() != tenant {
+		return &TenantError{tx.key(model)}
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+package sqlite
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrTenantManagerClosed is returned when opening a database after the manager has been closed.
+var ErrTenantManagerClosed = errors.New("sqlite: tenant manager is closed")
+
+type TenantOption func(opts *tenantOptions)
+
+type tenantOptions struct {
+	open        []OpenOption
+	migrations  []Migration
+	migrate     []MigrateOption
+	maxOpen     int
+	idleTimeout time.Duration
+}
+
+// WithTenantOpenOptions configures the options used to open the database of each tenant.
+func WithTenantOpenOptions(options ...OpenOption) TenantOption {
+	return func(opts *tenantOptions) {
+		opts.open = options
+	}
+}
+
+// WithTenantMigrations configures the migrations applied to the database of each tenant the first
+// time it is opened.
+func WithTenantMigrations(migrations []Migration, options ...MigrateOption) TenantOption {
+	return func(opts *tenantOptions) {
+		opts.migrations = migrations
+		opts.migrate = options
+	}
+}
+
+// WithMaxTenants configures the number of databases kept open at the same time. When it is
+// exceeded the least recently used one is closed. A limit below 1 keeps all of them open until
+// they are idle. By default it is 100.
+func WithMaxTenants(n int) TenantOption {
+	return func(opts *tenantOptions) {
+		opts.maxOpen = n
+	}
+}
+
+// WithTenantIdleTimeout configures how long a database is kept open without being requested.
+// A zero timeout keeps them open until they are evicted. By default it is 10 minutes.
+func WithTenantIdleTimeout(timeout time.Duration) TenantOption {
+	return func(opts *tenantOptions) {
+		opts.idleTimeout = timeout
+	}
+}
+
+var tenantNameRe = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// TenantManager opens a separate database file for each tenant inside a root directory. The
+// databases are opened and migrated lazily the first time they are requested, and closed when
+// they exceed the maximum number of open databases or stay idle for too long.
+//
+// Databases are leased to the callers, and are never closed while a lease is held. Release them
+// after each unit of work instead of storing them.
+type TenantManager struct {
+	root string
+	opts tenantOptions
+
+	mu      sync.Mutex
+	entries map[string]*tenantEntry
+	lru     *list.List
+	closed  bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type tenantEntry struct {
+	tenant   string
+	elem     *list.Element
+	lastUsed time.Time
+
+	// refs is the number of leases of the database that have not been released yet.
+	refs int
+
+	// ready is closed once the database has been opened and migrated, or has failed.
+	ready  chan struct{}
+	opened bool
+	db     *sqlx.DB
+	err    error
+}
+
+// NewTenantManager creates a manager that stores the database of each tenant in the file
+// <root>/<tenant>.db. Call Close to release the open databases.
+func NewTenantManager(root string, options ...TenantOption) *TenantManager {
+	opts := tenantOptions{
+		maxOpen:     100,
+		idleTimeout: 10 * time.Minute,
+	}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	m := &TenantManager{
+		root:    root,
+		opts:    opts,
+		entries: make(map[string]*tenantEntry),
+		lru:     list.New(),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if opts.idleTimeout > 0 {
+		go m.closeIdle()
+	} else {
+		close(m.done)
+	}
+	return m
+}
+
+// TenantDB is a database leased by a TenantManager. It stays open until it is released.
+type TenantDB struct {
+	*sqlx.DB
+
+	m       *TenantManager
+	entry   *tenantEntry
+	release sync.Once
+}
+
+// Release returns the database to the manager, that can close it once it is not leased anymore.
+// The database must not be used after releasing it. It is safe to call it more than once.
+func (db *TenantDB) Release() {
+	db.release.Do(func() {
+		db.m.release(db.entry)
+	})
+}
+
+// DB leases the database of the tenant assigned to the context with WithTenant.
+func (m *TenantManager) DB(ctx context.Context) (*TenantDB, error) {
+	tenant := ctx.Value(tenantKey{})
+	if tenant == nil {
+		return nil, ErrMissingTenant
+	}
+	name, ok := convertValue(reflect.TypeOf(""), tenant)
+	if !ok {
+		return nil, fmt.Errorf("invalid tenant type %T", tenant)
+	}
+	return m.Open(ctx, name.(string))
+}
+
+// Open leases the database of the tenant, opening and migrating it if needed.
+func (m *TenantManager) Open(ctx context.Context, tenant string) (*TenantDB, error) {
+	if !tenantNameRe.MatchString(tenant) {
+		return nil, fmt.Errorf("invalid tenant name %q", tenant)
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrTenantManagerClosed
+	}
+	entry, ok := m.entries[tenant]
+	if ok {
+		entry.refs++
+		entry.lastUsed = time.Now()
+		m.lru.MoveToFront(entry.elem)
+		m.mu.Unlock()
+
+		select {
+		case <-entry.ready:
+			if entry.err != nil {
+				m.release(entry)
+				return nil, entry.err
+			}
+			return m.lease(entry), nil
+		case <-ctx.Done():
+			m.release(entry)
+			return nil, ctx.Err()
+		}
+	}
+
+	entry = &tenantEntry{
+		tenant:   tenant,
+		lastUsed: time.Now(),
+		refs:     1,
+		ready:    make(chan struct{}),
+	}
+	entry.elem = m.lru.PushFront(entry)
+	m.entries[tenant] = entry
+	m.mu.Unlock()
+
+	// Other requests of the same tenant wait for the entry instead of opening the file again.
+	db, err := m.open(ctx, tenant)
+
+	m.mu.Lock()
+	if err == nil && m.closed {
+		err = errors.Join(ErrTenantManagerClosed, db.Close())
+		db = nil
+	}
+	entry.db, entry.err = db, err
+	if err != nil {
+		m.remove(entry)
+	} else {
+		entry.opened = true
+	}
+	close(entry.ready)
+	evicted := m.evict()
+	m.mu.Unlock()
+
+	// Errors closing evicted databases are not actionable by the caller of another tenant.
+	_ = closeEntries(evicted)
+
+	if err != nil {
+		return nil, err
+	}
+	return m.lease(entry), nil
+}
+
+func (m *TenantManager) lease(entry *tenantEntry) *TenantDB {
+	return &TenantDB{DB: entry.db, m: m, entry: entry}
+}
+
+// release ends a lease of the entry and closes the databases that are not needed anymore.
+func (m *TenantManager) release(entry *tenantEntry) {
+	m.mu.Lock()
+	entry.refs--
+	entry.lastUsed = time.Now()
+	var unused []*tenantEntry
+	if m.closed {
+		if entry.opened && entry.refs == 0 {
+			m.remove(entry)
+			unused = append(unused, entry)
+		}
+	} else {
+		unused = m.evict()
+	}
+	m.mu.Unlock()
+
+	_ = closeEntries(unused)
+}
+
+func (m *TenantManager) open(ctx context.Context, tenant string) (*sqlx.DB, error) {
+	db, err := Open(filepath.Join(m.root, tenant+".db"), m.opts.open...)
+	if err != nil {
+		return nil, err
+	}
+	if err := Migrate(ctx, db, m.opts.migrations, m.opts.migrate...); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("cannot migrate database of tenant %q: %w", tenant, err)
+	}
+	return db, nil
+}
+
+// remove forgets the entry. It must be called with the lock held.
+func (m *TenantManager) remove(entry *tenantEntry) {
+	m.lru.Remove(entry.elem)
+	if m.entries[entry.tenant] == entry {
+		delete(m.entries, entry.tenant)
+	}
+}
+
+// evict removes the least recently used databases that exceed the maximum. Databases still
+// being opened or leased are never evicted, so the maximum can be exceeded temporarily. It must
+// be called with the lock held.
+func (m *TenantManager) evict() []*tenantEntry {
+	var evicted []*tenantEntry
+	if m.opts.maxOpen < 1 {
+		return nil
+	}
+	for elem := m.lru.Back(); elem != nil && m.lru.Len() > m.opts.maxOpen; {
+		entry := elem.Value.(*tenantEntry)
+		elem = elem.Prev()
+		if entry.opened && entry.refs == 0 {
+			m.remove(entry)
+			evicted = append(evicted, entry)
+		}
+	}
+	return evicted
+}
+
+// closeIdle periodically closes the databases that have not been leased for longer than the
+// idle timeout.
+func (m *TenantManager) closeIdle() {
+	defer close(m.done)
+
+	// Very short timeouts would round the interval down to zero.
+	ticker := time.NewTicker(max(m.opts.idleTimeout/2, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-ticker.C:
+			m.mu.Lock()
+			var idle []*tenantEntry
+			for _, entry := range m.entries {
+				if entry.opened && entry.refs == 0 && now.Sub(entry.lastUsed) > m.opts.idleTimeout {
+					m.remove(entry)
+					idle = append(idle, entry)
+				}
+			}
+			m.mu.Unlock()
+
+			_ = closeEntries(idle)
+		}
+	}
+}
+
+// Close closes all the open databases. Databases being opened or leased when it is called are
+// closed as soon as they are ready or released.
+func (m *TenantManager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	var open []*tenantEntry
+	for _, entry := range m.entries {
+		if entry.opened && entry.refs == 0 {
+			m.remove(entry)
+			open = append(open, entry)
+		}
+	}
+	m.mu.Unlock()
+
+	close(m.stop)
+	<-m.done
+
+	return closeEntries(open)
+}
+
+func closeEntries(entries []*tenantEntry) error {
+	var multi []error
+	for _, entry := range entries {
+		if err := entry.db.Close(); err != nil {
+			multi = append(multi, fmt.Errorf("cannot close database of tenant %q: %w", entry.tenant, err))
+		}
+	}
+	return errors.Join(multi...)
+}
+
+// NewTenantRepo creates a repository for the models stored in a table of the database of the
+// tenant assigned to the context with WithTenant. Release the returned database when the
+// repository is not used anymore.
+func NewTenantRepo[T any](ctx context.Context, m *TenantManager, cnf RepoConfig[T]) (*RepoGeneric[T], *TenantDB, error) {
+	db, err := m.DB(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	repo, err := NewRepoGeneric(db.DB, cnf)
+	if err != nil {
+		db.Release()
+		return nil, nil, err
+	}
+	return repo, db, nil
+}
@@ -0,0 +1,210 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+var tenantMigrations = []Migration{
+	func(ctx context.Context, db *sqlx.DB) error {
+		_, err := db.ExecContext(ctx, "CREATE TABLE TestModels (Name TEXT NOT NULL PRIMARY KEY, Value TEXT)")
+		return err
+	},
+}
+
+func TestTenantManager(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+
+	m := NewTenantManager(root, WithTenantMigrations(tenantMigrations))
+	defer m.Close()
+
+	foo := WithTenant(ctx, "foo")
+	bar := WithTenant(ctx, "bar")
+
+	repo, db, err := NewTenantRepo(foo, m, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.Put(foo, &testModel{Name: "foo", Value: "foo-value"}))
+	db.Release()
+
+	repo, db, err = NewTenantRepo(bar, m, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+	_, err = repo.Get(bar, "foo")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	db.Release()
+
+	require.FileExists(t, filepath.Join(root, "foo.db"))
+	require.FileExists(t, filepath.Join(root, "bar.db"))
+
+	db, err = m.DB(foo)
+	require.NoError(t, err)
+	defer db.Release()
+	other, err := m.Open(ctx, "foo")
+	require.NoError(t, err)
+	defer other.Release()
+	require.Same(t, db.DB, other.DB)
+
+	_, err = m.DB(ctx)
+	require.ErrorIs(t, err, ErrMissingTenant)
+	_, err = m.Open(ctx, "../foo")
+	require.EqualError(t, err, `invalid tenant name "../foo"`)
+	_, err = m.DB(WithTenant(ctx, 1))
+	require.EqualError(t, err, "invalid tenant type int")
+}
+
+func TestTenantManagerEvict(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+
+	m := NewTenantManager(root, WithTenantMigrations(tenantMigrations), WithMaxTenants(2))
+	defer m.Close()
+
+	foo, err := m.Open(ctx, "foo")
+	require.NoError(t, err)
+	_, err = foo.ExecContext(ctx, "INSERT INTO TestModels (Name, Value) VALUES ('foo', 'foo-value')")
+	require.NoError(t, err)
+	foo.Release()
+	bar, err := m.Open(ctx, "bar")
+	require.NoError(t, err)
+	bar.Release()
+	foo, err = m.Open(ctx, "foo")
+	require.NoError(t, err)
+	foo.Release()
+
+	baz, err := m.Open(ctx, "baz")
+	require.NoError(t, err)
+	require.Len(t, m.entries, 2)
+	require.Contains(t, m.entries, "foo")
+	require.Contains(t, m.entries, "baz")
+	require.Error(t, bar.PingContext(ctx))
+	require.NoError(t, foo.PingContext(ctx))
+	baz.Release()
+
+	require.NoError(t, m.Close())
+	require.Error(t, baz.PingContext(ctx))
+	_, err = m.Open(ctx, "foo")
+	require.ErrorIs(t, err, ErrTenantManagerClosed)
+
+	m = NewTenantManager(root, WithTenantMigrations(tenantMigrations), WithMaxTenants(2))
+	defer m.Close()
+	foo, err = m.Open(ctx, "foo")
+	require.NoError(t, err)
+	defer foo.Release()
+	var value string
+	require.NoError(t, foo.GetContext(ctx, &value, "SELECT Value FROM TestModels WHERE Name = 'foo'"))
+	require.Equal(t, value, "foo-value")
+}
+
+func TestTenantManagerEvictLeased(t *testing.T) {
+	ctx := context.Background()
+
+	m := NewTenantManager(t.TempDir(), WithTenantMigrations(tenantMigrations), WithMaxTenants(1))
+	defer m.Close()
+
+	foo, err := m.Open(ctx, "foo")
+	require.NoError(t, err)
+
+	bar, err := m.Open(ctx, "bar")
+	require.NoError(t, err)
+	bar.Release()
+	require.Len(t, m.entries, 1)
+
+	baz, err := m.Open(ctx, "baz")
+	require.NoError(t, err)
+	require.Len(t, m.entries, 2)
+	_, err = foo.ExecContext(ctx, "INSERT INTO TestModels (Name, Value) VALUES ('foo', 'foo-value')")
+	require.NoError(t, err)
+
+	foo.Release()
+	foo.Release()
+	require.Error(t, foo.PingContext(ctx))
+	require.Len(t, m.entries, 1)
+	require.NoError(t, baz.PingContext(ctx))
+	baz.Release()
+}
+
+func TestTenantManagerIdle(t *testing.T) {
+	ctx := context.Background()
+
+	m := NewTenantManager(t.TempDir(), WithTenantIdleTimeout(20*time.Millisecond))
+	defer m.Close()
+
+	db, err := m.Open(ctx, "foo")
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, db.PingContext(ctx))
+
+	db.Release()
+	require.Eventually(t, func() bool {
+		return db.PingContext(ctx) != nil
+	}, time.Second, 10*time.Millisecond)
+
+	m.mu.Lock()
+	require.Empty(t, m.entries)
+	m.mu.Unlock()
+}
+
+func TestTenantManagerCloseLeased(t *testing.T) {
+	ctx := context.Background()
+
+	m := NewTenantManager(t.TempDir())
+
+	db, err := m.Open(ctx, "foo")
+	require.NoError(t, err)
+	require.NoError(t, m.Close())
+	require.NoError(t, db.PingContext(ctx))
+
+	db.Release()
+	require.Error(t, db.PingContext(ctx))
+	require.Empty(t, m.entries)
+}
+
+func TestTenantManagerMigrationError(t *testing.T) {
+	ctx := context.Background()
+
+	migrations := []Migration{
+		func(ctx context.Context, db *sqlx.DB) error {
+			_, err := db.ExecContext(ctx, "CREATE TABLE")
+			return err
+		},
+	}
+	m := NewTenantManager(t.TempDir(), WithTenantMigrations(migrations))
+	defer m.Close()
+
+	_, err := m.Open(ctx, "foo")
+	require.ErrorContains(t, err, `cannot migrate database of tenant "foo"`)
+	require.Empty(t, m.entries)
+}
+
+func TestTenantManagerShortIdleTimeout(t *testing.T) {
+	m := NewTenantManager(t.TempDir(), WithTenantIdleTimeout(time.Nanosecond))
+	require.NoError(t, m.Close())
+}
+
+func TestTenantManagerNoLimit(t *testing.T) {
+	ctx := context.Background()
+
+	m := NewTenantManager(t.TempDir(), WithMaxTenants(0))
+	defer m.Close()
+
+	foo, err := m.Open(ctx, "foo")
+	require.NoError(t, err)
+	foo.Release()
+	bar, err := m.Open(ctx, "bar")
+	require.NoError(t, err)
+	bar.Release()
+	require.NoError(t, foo.PingContext(ctx))
+	require.Len(t, m.entries, 2)
+}
@@ -0,0 +1,236 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantScope(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testTenant]{
+		Table:      "TestTenants",
+		PrimaryKey: "ID",
+		Tenant:     "TenantID",
+	})
+	require.NoError(t, err)
+
+	foo := WithTenant(ctx, "foo")
+	bar := WithTenant(ctx, "bar")
+
+	model := &testTenant{ID: "foo-1", Value: "foo-value"}
+	require.NoError(t, repo.Put(foo, model))
+	require.Equal(t, model.TenantID, "foo")
+	require.NoError(t, repo.Put(foo, &testTenant{ID: "foo-2", TenantID: "foo"}))
+	require.NoError(t, repo.Put(bar, &testTenant{ID: "bar-1"}))
+
+	n, err := repo.Count(foo)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 2)
+
+	models, err := repo.List(bar)
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+	require.Equal(t, models[0].ID, "bar-1")
+
+	_, err = repo.Get(bar, "foo-1")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	model, err = repo.Get(foo, "foo-1")
+	require.NoError(t, err)
+	require.Equal(t, model.Value, "foo-value")
+
+	exists, err := repo.Exists(bar, "foo-1")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	n, err = repo.ForTenant("bar").Count(foo)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+
+	_, err = repo.Count(ctx)
+	require.ErrorIs(t, err, ErrMissingTenant)
+	require.ErrorIs(t, repo.Put(ctx, &testTenant{ID: "baz-1"}), ErrMissingTenant)
+
+	err = repo.UpdateFields(bar, "foo-1", map[string]any{"Value": "bar-value"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	require.Error(t, repo.UpdateFields(foo, "foo-1", map[string]any{"TenantID": "bar"}))
+	require.NoError(t, repo.UpdateFields(foo, "foo-1", map[string]any{"Value": "foo-updated"}))
+
+	require.NoError(t, repo.DeleteKey(bar, "foo-1"))
+	n, err = repo.DeleteWhere(bar, "ID LIKE ?", "%-1")
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+
+	n, err = repo.ForTenant("foo").Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 2)
+}
+
+func TestTenantWriteOtherTenant(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testTenant]{
+		Table:      "TestTenants",
+		PrimaryKey: "ID",
+		Tenant:     "TenantID",
+	})
+	require.NoError(t, err)
+
+	foo := WithTenant(ctx, "foo")
+	bar := WithTenant(ctx, "bar")
+
+	require.NoError(t, repo.Put(foo, &testTenant{ID: "foo-1", Value: "foo-value"}))
+
+	var tenantErr *TenantError
+	err = repo.Put(bar, &testTenant{ID: "bar-1", TenantID: "foo"})
+	require.ErrorAs(t, err, &tenantErr)
+	require.EqualError(t, err, `sqlite: "bar-1" belongs to another tenant`)
+
+	err = repo.Put(bar, &testTenant{ID: "foo-1", Value: "bar-value"})
+	require.ErrorAs(t, err, &tenantErr)
+	require.Equal(t, tenantErr.Key, "foo-1")
+
+	model, err := repo.Get(foo, "foo-1")
+	require.NoError(t, err)
+	require.Equal(t, model.Value, "foo-value")
+	require.Equal(t, model.TenantID, "foo")
+}
+
+func TestTenantExistsQuery(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testTenant]{
+		Table:      "TestTenants",
+		PrimaryKey: "ID",
+		Tenant:     "TenantID",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(WithTenant(ctx, "foo"), &testTenant{ID: "foo-1"}))
+
+	exists, err := repo.ForTenant("foo").ExistsQuery().QueryValue(ctx, sql.Named("ID", "foo-1"))
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	exists, err = repo.ExistsQuery().QueryValue(ctx, sql.Named("ID", "foo-1"), sql.Named("TenantID", "bar"))
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	exists, err = repo.ExistsQuery().QueryValue(ctx, sql.Named("TenantID", "foo"), sql.Named("ID", "foo-1"))
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	_, err = repo.ExistsQuery().QueryValue(ctx, sql.Named("ID", "foo-1"))
+	require.EqualError(t, err, `arg "TenantID" is not bound yet`)
+}
+
+func TestTenantSingletonListIter(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoSingleton(db, RepoConfig[testTenant]{
+		Table:      "TestTenants",
+		PrimaryKey: "ID",
+		Tenant:     "TenantID",
+	})
+	require.NoError(t, err)
+
+	foo := WithTenant(ctx, "foo")
+	require.NoError(t, repo.Put(foo, &testTenant{ID: "foo-1"}))
+	require.NoError(t, repo.Put(WithTenant(ctx, "bar"), &testTenant{ID: "bar-1"}))
+
+	var models []*testTenant
+	repo.ListIter(foo)(func(model *testTenant, err error) bool {
+		require.NoError(t, err)
+		models = append(models, model)
+		return true
+	})
+	require.Len(t, models, 1)
+	require.Equal(t, models[0].ID, "foo-1")
+
+	var errs []error
+	repo.ListIter(ctx)(func(model *testTenant, err error) bool {
+		errs = append(errs, err)
+		return true
+	})
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs[0], ErrMissingTenant)
+}
+
+func TestTenantHistory(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	require.NoError(t, AuditMigration("TestAudit")(ctx, db))
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testTenant]{
+		Table:      "TestTenants",
+		PrimaryKey: "ID",
+		Tenant:     "TenantID",
+		Audit:      "TestAudit",
+	})
+	require.NoError(t, err)
+
+	foo := WithTenant(ctx, "foo")
+	require.NoError(t, repo.Put(foo, &testTenant{ID: "1", Value: "secret"}))
+	require.NoError(t, repo.DeleteKey(foo, "1"))
+	require.NoError(t, repo.Put(WithTenant(ctx, "bar"), &testTenant{ID: "1", Value: "bar-value"}))
+
+	history, err := repo.History(foo, "1")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, history[0].Operation, AuditInsert)
+	require.Equal(t, history[1].Operation, AuditDelete)
+
+	history, err = repo.History(WithTenant(ctx, "bar"), "1")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.JSONEq(t, string(history[0].After), `{"ID":"1","TenantID":"bar","Value":"bar-value"}`)
+
+	history, err = repo.History(WithTenant(ctx, "baz"), "1")
+	require.NoError(t, err)
+	require.Empty(t, history)
+
+	_, err = repo.History(ctx, "1")
+	require.ErrorIs(t, err, ErrMissingTenant)
+}
+
+type testTenantHidden struct {
+	ID       string
+	TenantID string `json:"-"`
+	Value    string
+}
+
+func TestTenantHistoryHiddenColumn(t *testing.T) {
+	ctx := WithTenant(context.Background(), "foo")
+	db := connectDB(t)
+	defer db.Close()
+
+	require.NoError(t, AuditMigration("TestAudit")(ctx, db))
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testTenantHidden]{
+		Table:      "TestTenants",
+		PrimaryKey: "ID",
+		Tenant:     "TenantID",
+		Audit:      "TestAudit",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Put(ctx, &testTenantHidden{ID: "1", Value: "foo-value"}))
+
+	history, err := repo.History(ctx, "1")
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.JSONEq(t, string(history[0].After), `{"ID":"1","TenantID":"foo","Value":"foo-value"}`)
+}
@@ -2,58 +2,759 @@ package sqlite
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// Tx is a typed view of a transaction for the model of a repository.
 type Tx[T any] struct {
-	db  *sqlx.DB
-	tx  *sqlx.Tx
-	cnf RepoConfig[T]
+	shared      *SharedTx
+	cnf         RepoConfig[T]
+	withDeleted bool
+	tenant      any
 }
 
 func newTx[T any](ctx context.Context, db *sqlx.DB, cnf RepoConfig[T]) (*Tx[T], error) {
-	tx, err := db.BeginTxx(ctx, nil)
+	shared, err := BeginTx(ctx, db)
 	if err != nil {
-		return nil, fmt.Errorf("cannot begin transaction: %w", err)
+		return nil, err
 	}
-
 	return &Tx[T]{
-		db:  db,
-		tx:  tx,
-		cnf: cnf,
+		shared: shared,
+		cnf:    cnf,
 	}, nil
 }
 
+func (tx *Tx[T]) store() store[T] {
+	return store[T]{db: tx.shared.db, ext: tx.shared, cnf: tx.cnf, prefix: "Tx", withDeleted: tx.withDeleted, tenant: tx.tenant}
+}
+
+// Shared returns the underlying transaction so other repositories can join it with WithTx.
+func (tx *Tx[T]) Shared() *SharedTx {
+	return tx.shared
+}
+
+// AfterCommit registers fn to run once the underlying transaction has been committed successfully.
+// It is discarded if the transaction is rolled back.
+func (tx *Tx[T]) AfterCommit(fn func()) {
+	tx.shared.AfterCommit(fn)
+}
+
+// Commit commits the underlying transaction, including the changes made through other
+// repositories that share it.
 func (tx *Tx[T]) Commit() error {
-	return tx.tx.Commit()
+	return tx.shared.Commit()
 }
 
+// Rollback aborts the underlying transaction, including the changes made through other
+// repositories that share it.
 func (tx *Tx[T]) Rollback() error {
-	return tx.tx.Rollback()
+	return tx.shared.Rollback()
 }
 
+// Put stores the model replacing any previous row with the same key. If a Version column is
+// configured it fails with a ConflictError when the stored version does not match.
+//
+// REPLACE deletes the previous row before inserting the new one, which triggers ON DELETE
+// foreign key actions. Use Upsert or Update to modify rows referenced by other tables. Models
+// with readonly columns are written like Upsert instead, so the stored values of those columns
+// are not reset to their defaults.
 func (tx *Tx[T]) Put(ctx context.Context, model *T) error {
-	if err := runBeforePut(ctx, tx, tx.cnf.Hooks, model); err != nil {
+	return tx.write(ctx, model, func() error {
+		if tx.cnf.Version != "" {
+			return tx.putVersion(ctx, "Tx.Put", model)
+		}
+		if hasReadOnlyCols(tx.shared.db, reflect.TypeOf(model).Elem()) {
+			return tx.upsert(ctx, "Tx.Put", model)
+		}
+
+		cols, values := listWriteCols(tx.shared.db, model)
+		cols, values, auto := tx.omitAutoKey(model, cols, values)
+		q, args, err := sqlx.In(fmt.Sprintf(`REPLACE INTO %s (%s) VALUES (?)`, tx.cnf.Table, strings.Join(cols, ",")), values)
+		if err != nil {
+			return fmt.Errorf("cannot prepare sql statement: %w", err)
+		}
+		tx.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "Tx.Put"), slog.String("q", q))
+		result, err := tx.shared.execPrepared(ctx, q, args...)
+		if err != nil {
+			return fmt.Errorf("cannot execute query: %w", err)
+		}
+		if auto {
+			return tx.setAutoKey(model, result)
+		}
+		return nil
+	})
+}
+
+// Insert stores a new model. It fails with an AlreadyExistsError if the key is already in use.
+func (tx *Tx[T]) Insert(ctx context.Context, model *T) error {
+	return tx.write(ctx, model, func() error {
+		version, err := tx.versionField(model)
+		if err != nil {
+			return err
+		}
+		if err := tx.insert(ctx, "Tx.Insert", model, 1); err != nil {
+			if isPrimaryKeyConflict(err) {
+				return fmt.Errorf("%w: %w", &AlreadyExistsError{tx.key(model)}, err)
+			}
+			return fmt.Errorf("cannot execute query: %w", err)
+		}
+		if version.IsValid() {
+			version.SetInt(1)
+		}
+		return nil
+	})
+}
+
+// Update modifies an existing model. It fails with a MissingKeyError if the key does not exist.
+// If a Version column is configured it fails with a ConflictError when the stored version does
+// not match.
+func (tx *Tx[T]) Update(ctx context.Context, model *T) error {
+	return tx.write(ctx, model, func() error {
+		version, err := tx.versionField(model)
+		if err != nil {
+			return err
+		}
+		var current int64
+		if version.IsValid() {
+			current = version.Int()
+		}
+		updated, err := tx.update(ctx, "Tx.Update", model, current)
+		if err != nil {
+			return fmt.Errorf("cannot execute query: %w", err)
+		}
+		if !updated {
+			if version.IsValid() {
+				var exists bool
+				q := fmt.Sprintf("SELECT COUNT(*) > 0 FROM %s WHERE %s", tx.cnf.Table, tx.store().pkWhere())
+				if err := tx.shared.GetContext(ctx, &exists, q, tx.store().keyArgs(tx.key(model))...); err != nil {
+					return fmt.Errorf("cannot execute query: %w", err)
+				}
+				if exists {
+					return &ConflictError{tx.key(model)}
+				}
+			}
+			return fmt.Errorf("%w: %w", &MissingKeyError{tx.key(model)}, sql.ErrNoRows)
+		}
+		if version.IsValid() {
+			version.SetInt(current + 1)
+		}
+		return nil
+	})
+}
+
+// Upsert inserts the model or updates the existing row in place if the key is already in use.
+// Unlike Put it does not delete the previous row, so foreign keys are not affected. If a Version
+// column is configured it behaves like Put.
+func (tx *Tx[T]) Upsert(ctx context.Context, model *T) error {
+	return tx.write(ctx, model, func() error {
+		if tx.cnf.Version != "" {
+			return tx.putVersion(ctx, "Tx.Upsert", model)
+		}
+		return tx.upsert(ctx, "Tx.Upsert", model)
+	})
+}
+
+// upsert inserts the model or updates the writable columns of the existing row.
+func (tx *Tx[T]) upsert(ctx context.Context, method string, model *T) error {
+	cols, values := listWriteCols(tx.shared.db, model)
+	cols, values, auto := tx.omitAutoKey(model, cols, values)
+	var sets []string
+	for _, col := range cols {
+		if !slices.Contains(tx.cnf.PrimaryKeys, col) {
+			sets = append(sets, fmt.Sprintf("%s = excluded.%s", col, col))
+		}
+	}
+	q := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (?) ON CONFLICT(%s) DO`, tx.cnf.Table, strings.Join(cols, ","), strings.Join(tx.cnf.PrimaryKeys, ","))
+	if len(sets) > 0 {
+		q += " UPDATE SET " + strings.Join(sets, ", ")
+	} else {
+		q += " NOTHING"
+	}
+	q, args, err := sqlx.In(q, values)
+	if err != nil {
+		return fmt.Errorf("cannot prepare sql statement: %w", err)
+	}
+	tx.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", method), slog.String("q", q))
+	result, err := tx.shared.execPrepared(ctx, q, args...)
+	if err != nil {
+		return fmt.Errorf("cannot execute query: %w", err)
+	}
+	if auto {
+		return tx.setAutoKey(model, result)
+	}
+	return nil
+}
+
+// UpdateFields modifies only the columns in fields for the row with the key, without reading
+// the model first. It fails with a MissingKeyError if the key does not exist. Hooks are not run
+// because there is no model involved. If a Version column is configured it is incremented.
+func (tx *Tx[T]) UpdateFields(ctx context.Context, key any, fields map[string]any) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("no fields to update")
+	}
+	key, err := tx.store().keyValue(key)
+	if err != nil {
 		return err
 	}
 
-	cols, values := listCols(tx.db, model)
-	q, args, err := sqlx.In(fmt.Sprintf(`REPLACE INTO %s (%s) VALUES (?)`, tx.cnf.Table, strings.Join(cols, ",")), values)
+	known := make(map[string]bool)
+	for _, fi := range modelFields(tx.shared.db, reflect.TypeOf((*T)(nil)).Elem()) {
+		_, readonly := fi.Options[tagReadOnly]
+		known[fi.Path] = !readonly
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		writable, ok := known[name]
+		if !ok {
+			return fmt.Errorf("unknown column %q in table %s", name, tx.cnf.Table)
+		}
+		if !writable || slices.Contains(tx.cnf.PrimaryKeys, name) || name == tx.cnf.Version || name == tx.cnf.Tenant {
+			return fmt.Errorf("cannot update column %q", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sets []string
+	var args []any
+	for _, name := range names {
+		sets = append(sets, name+" = ?")
+		args = append(args, fields[name])
+	}
+	if tx.cnf.Version != "" {
+		sets = append(sets, fmt.Sprintf("%s = %s + 1", tx.cnf.Version, tx.cnf.Version))
+	}
+	if _, ok := fields[tx.cnf.UpdatedAt]; tx.cnf.UpdatedAt != "" && !ok {
+		sets = append(sets, tx.cnf.UpdatedAt+" = ?")
+		args = append(args, tx.cnf.Clock())
+	}
+	conds, keyArgs, err := tx.store().scope(ctx, []string{tx.store().pkWhere()}, tx.store().keyArgs(key))
 	if err != nil {
-		return fmt.Errorf("cannot prepare sql statement: %w", err)
+		return err
 	}
-	tx.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "Tx.Put"), slog.String("q", q))
-	if _, err := tx.tx.ExecContext(ctx, q, args...); err != nil {
+	args = append(args, keyArgs...)
+
+	before, err := tx.auditBefore(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	q := fmt.Sprintf("UPDATE %s SET %s WHERE %s", tx.cnf.Table, strings.Join(sets, ", "), strings.Join(conds, " AND "))
+	tx.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "Tx.UpdateFields"), slog.String("q", q), slog.Any("key", key))
+	result, err := tx.shared.execPrepared(ctx, q, args...)
+	if err != nil {
 		return fmt.Errorf("cannot execute query: %w", err)
 	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("cannot read affected rows: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%w: %w", &MissingKeyError{key}, sql.ErrNoRows)
+	}
+	tx.shared.recordChange(ChangeEvent{Table: tx.cnf.Table, Key: key, Op: ChangePut})
+	return tx.auditAfter(ctx, AuditUpdate, key, before)
+}
 
+// write runs the statements of fn between the put hooks.
+func (tx *Tx[T]) write(ctx context.Context, model *T, fn func() error) error {
+	if err := runBeforePut(ctx, tx, tx.cnf.Hooks, model); err != nil {
+		return err
+	}
+	if err := tx.checkTenant(ctx, model); err != nil {
+		return err
+	}
+	if err := tx.stampTimes(ctx, model); err != nil {
+		return err
+	}
+	before, err := tx.auditBefore(ctx, tx.key(model))
+	if err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	op := AuditUpdate
+	if before == nil {
+		op = AuditInsert
+	}
+	if err := tx.auditAfter(ctx, op, tx.key(model), before); err != nil {
+		return err
+	}
+	tx.shared.recordChange(ChangeEvent{Table: tx.cnf.Table, Key: tx.key(model), Op: ChangePut})
 	if err := runAfterPut(ctx, tx.cnf.Hooks, model); err != nil {
 		return err
 	}
+	registerAfterCommit(ctx, tx, tx.cnf.Hooks, model)
+	return nil
+}
+
+// timeField returns the time.Time field of the model for the column.
+func (tx *Tx[T]) timeField(model *T, col string) (reflect.Value, error) {
+	field := tx.shared.db.Mapper.FieldByName(reflect.ValueOf(model), col)
+	if !field.IsValid() || field.Type() != reflect.TypeOf(time.Time{}) {
+		return reflect.Value{}, fmt.Errorf("timestamp column %q must be a time.Time field", col)
+	}
+	return field, nil
+}
+
+// stampTimes fills the CreatedAt and UpdatedAt columns of the model if they are configured.
+func (tx *Tx[T]) stampTimes(ctx context.Context, model *T) error {
+	if tx.cnf.CreatedAt == "" && tx.cnf.UpdatedAt == "" {
+		return nil
+	}
+	now := tx.cnf.Clock()
+
+	if tx.cnf.UpdatedAt != "" {
+		field, err := tx.timeField(model, tx.cnf.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(now))
+	}
+
+	if tx.cnf.CreatedAt != "" {
+		field, err := tx.timeField(model, tx.cnf.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		var created time.Time
+		q := fmt.Sprintf("SELECT %s FROM %s WHERE %s", tx.cnf.CreatedAt, tx.cnf.Table, tx.store().pkWhere())
+		tx.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "Tx.stampTimes"), slog.String("q", q))
+		if err := tx.shared.GetContext(ctx, &created, q, tx.store().keyArgs(tx.key(model))...); err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("cannot execute query: %w", err)
+			}
+			created = now
+		}
+		field.Set(reflect.ValueOf(created))
+	}
+
+	return nil
+}
+
+func (tx *Tx[T]) key(model *T) any {
+	return tx.store().modelKey(model)
+}
+
+// versionField returns the version field of the model, or an invalid value if there is no
+// Version column configured.
+func (tx *Tx[T]) versionField(model *T) (reflect.Value, error) {
+	if tx.cnf.Version == "" {
+		return reflect.Value{}, nil
+	}
+	version := tx.shared.db.Mapper.FieldByName(reflect.ValueOf(model), tx.cnf.Version)
+	if !version.IsValid() || !version.CanInt() {
+		return reflect.Value{}, fmt.Errorf("version column %q must be an integer field", tx.cnf.Version)
+	}
+	return version, nil
+}
+
+// writeCols returns the columns and values of the model to write, replacing the value of the
+// Version column if there is one configured.
+func (tx *Tx[T]) writeCols(model *T, version int64) ([]string, []any) {
+	cols, values := listWriteCols(tx.shared.db, model)
+	if tx.cnf.Version != "" {
+		for i, col := range cols {
+			if col == tx.cnf.Version {
+				values[i] = version
+			}
+		}
+	}
+	return cols, values
+}
+
+// omitAutoKey removes the primary key from the columns if it has to be assigned by SQLite and
+// reports if it was removed.
+func (tx *Tx[T]) omitAutoKey(model *T, cols []string, values []any) ([]string, []any, bool) {
+	if !tx.cnf.AutoIncrement || !tx.store().getPK(model).IsZero() {
+		return cols, values, false
+	}
+	for i, col := range cols {
+		if col == tx.cnf.PrimaryKey {
+			cols = append(cols[:i:i], cols[i+1:]...)
+			values = append(values[:i:i], values[i+1:]...)
+			break
+		}
+	}
+	return cols, values, true
+}
 
+// setAutoKey stores the primary key assigned by SQLite in the model.
+func (tx *Tx[T]) setAutoKey(model *T, result sql.Result) error {
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("cannot read inserted key: %w", err)
+	}
+	tx.store().getPK(model).SetInt(id)
+	return nil
+}
+
+// insert runs an INSERT statement for the model and returns the driver error without wrapping it.
+func (tx *Tx[T]) insert(ctx context.Context, method string, model *T, version int64) error {
+	cols, values := tx.writeCols(model, version)
+	cols, values, auto := tx.omitAutoKey(model, cols, values)
+	q, args, err := sqlx.In(fmt.Sprintf(`INSERT INTO %s (%s) VALUES (?)`, tx.cnf.Table, strings.Join(cols, ",")), values)
+	if err != nil {
+		return err
+	}
+	tx.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", method), slog.String("q", q))
+	result, err := tx.shared.execPrepared(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	if auto {
+		return tx.setAutoKey(model, result)
+	}
 	return nil
 }
+
+// update runs an UPDATE statement for the model and reports if any row was modified. If a Version
+// column is configured only the row with the current version is updated.
+func (tx *Tx[T]) update(ctx context.Context, method string, model *T, current int64) (bool, error) {
+	cols, values := tx.writeCols(model, current+1)
+	var sets []string
+	var args []any
+	for i, col := range cols {
+		if slices.Contains(tx.cnf.PrimaryKeys, col) {
+			continue
+		}
+		sets = append(sets, col+" = ?")
+		args = append(args, values[i])
+	}
+	if len(sets) == 0 {
+		// Every column is part of the key, so there is nothing to change if the row exists.
+		var exists bool
+		q := fmt.Sprintf("SELECT COUNT(*) > 0 FROM %s WHERE %s", tx.cnf.Table, tx.store().pkWhere())
+		tx.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", method), slog.String("q", q))
+		if err := tx.shared.GetContext(ctx, &exists, q, tx.store().keyArgs(tx.key(model))...); err != nil {
+			return false, err
+		}
+		return exists, nil
+	}
+	q := fmt.Sprintf(`UPDATE %s SET %s WHERE %s`, tx.cnf.Table, strings.Join(sets, ", "), tx.store().pkWhere())
+	args = append(args, tx.store().keyArgs(tx.key(model))...)
+	if tx.cnf.Version != "" {
+		q += fmt.Sprintf(" AND %s = ?", tx.cnf.Version)
+		args = append(args, current)
+	}
+	tx.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", method), slog.String("q", q))
+	result, err := tx.shared.execPrepared(ctx, q, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// putVersion inserts the model if its version is zero, or updates it if the stored version
+// matches the one in the model. The version of the model is incremented if the write succeeds.
+func (tx *Tx[T]) putVersion(ctx context.Context, method string, model *T) error {
+	version, err := tx.versionField(model)
+	if err != nil {
+		return err
+	}
+	current := version.Int()
+
+	if current == 0 {
+		if err := tx.insert(ctx, method, model, 1); err != nil {
+			if isPrimaryKeyConflict(err) {
+				return fmt.Errorf("%w: %w", &ConflictError{tx.key(model)}, err)
+			}
+			return fmt.Errorf("cannot execute query: %w", err)
+		}
+	} else {
+		updated, err := tx.update(ctx, method, model, current)
+		if err != nil {
+			return fmt.Errorf("cannot execute query: %w", err)
+		}
+		if !updated {
+			return &ConflictError{tx.key(model)}
+		}
+	}
+	version.SetInt(current + 1)
+	return nil
+}
+
+// PutMulti stores all the models in the transaction. It tries to store every model and returns
+// a joined error with a PutError for each one that failed.
+func (tx *Tx[T]) PutMulti(ctx context.Context, models []*T) error {
+	var multi []error
+	for i, model := range models {
+		if err := tx.Put(ctx, model); err != nil {
+			multi = append(multi, &PutError{Index: i, Key: tx.key(model), Err: err})
+		}
+	}
+	return errors.Join(multi...)
+}
+
+func (tx *Tx[T]) Count(ctx context.Context) (int64, error) {
+	return tx.store().count(ctx)
+}
+
+func (tx *Tx[T]) Get(ctx context.Context, key any) (*T, error) {
+	return tx.store().get(ctx, key)
+}
+
+// GetMulti returns the models of the keys in the same order. Keys must be a slice.
+func (tx *Tx[T]) GetMulti(ctx context.Context, keys any) ([]*T, error) {
+	return tx.store().getMulti(ctx, keys)
+}
+
+func (tx *Tx[T]) Exists(ctx context.Context, key any) (bool, error) {
+	return tx.store().exists(ctx, key)
+}
+
+func (tx *Tx[T]) Query(ctx context.Context, query string, args ...interface{}) (*T, error) {
+	return tx.store().query(ctx, query, args...)
+}
+
+func (tx *Tx[T]) QueryList(ctx context.Context, query string, args ...interface{}) ([]*T, error) {
+	return tx.store().queryList(ctx, query, args...)
+}
+
+// History returns the changes of the row with the key recorded in the audit table. Repositories
+// with a Tenant column only return the changes made while the row belonged to the tenant.
+func (tx *Tx[T]) History(ctx context.Context, key any) ([]*AuditEntry, error) {
+	return tx.store().history(ctx, key)
+}
+
+func (tx *Tx[T]) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tx.store().exec(ctx, query, args...)
+}
+
+// Delete removes the model from the table. It does nothing if the row does not exist. If a
+// SoftDelete column is configured the row is only marked as deleted.
+func (tx *Tx[T]) Delete(ctx context.Context, model *T) error {
+	// The delete hooks only run for rows that exist and are not deleted yet.
+	if tx.hasDeleteHooks() {
+		s := tx.store()
+		s.withDeleted = false
+		exists, err := s.exists(ctx, tx.key(model))
+		if err != nil || !exists {
+			return err
+		}
+	}
+	_, err := tx.deleteModels(ctx, "Tx.Delete", false, []*T{model})
+	return err
+}
+
+// DeleteKey removes the row with the key. It does nothing if the key does not exist. If a
+// SoftDelete column is configured the row is only marked as deleted.
+func (tx *Tx[T]) DeleteKey(ctx context.Context, key any) error {
+	key, err := tx.store().keyValue(key)
+	if err != nil {
+		return err
+	}
+	_, err = tx.deleteWhere(ctx, "Tx.DeleteKey", false, tx.store().pkWhere(), tx.store().keyArgs(key)...)
+	return err
+}
+
+// DeleteMulti removes the rows with the keys and returns the number of deleted rows. Keys must
+// be a slice. If a SoftDelete column is configured the rows are only marked as deleted.
+func (tx *Tx[T]) DeleteMulti(ctx context.Context, keys any) (int64, error) {
+	values, err := tx.store().keyValues(keys)
+	if err != nil {
+		return 0, err
+	}
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	where, args := tx.store().pkIn(values)
+	return tx.deleteWhere(ctx, "Tx.DeleteMulti", false, where, args...)
+}
+
+// DeleteWhere removes the rows matching the WHERE condition and returns the number of deleted
+// rows. If a SoftDelete column is configured the rows are only marked as deleted.
+func (tx *Tx[T]) DeleteWhere(ctx context.Context, where string, args ...interface{}) (int64, error) {
+	where = normalizeQuery(where)
+	if where == "" {
+		return 0, fmt.Errorf("empty where condition")
+	}
+	return tx.deleteWhere(ctx, "Tx.DeleteWhere", false, "("+where+")", args...)
+}
+
+// Purge permanently removes the row with the key, even if it was soft deleted before.
+func (tx *Tx[T]) Purge(ctx context.Context, key any) error {
+	key, err := tx.store().keyValue(key)
+	if err != nil {
+		return err
+	}
+	_, err = tx.deleteWhere(ctx, "Tx.Purge", true, tx.store().pkWhere(), tx.store().keyArgs(key)...)
+	return err
+}
+
+// PurgeDeleted permanently removes the rows soft deleted before the time and returns the number
+// of removed rows.
+func (tx *Tx[T]) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	if tx.cnf.SoftDelete == "" {
+		return 0, fmt.Errorf("soft delete is not configured for table %s", tx.cnf.Table)
+	}
+	return tx.deleteWhere(ctx, "Tx.PurgeDeleted", true, fmt.Sprintf("%s < ?", tx.cnf.SoftDelete), before)
+}
+
+// Restore clears the soft delete mark of the row with the key. It fails with a MissingKeyError
+// if the key does not exist.
+func (tx *Tx[T]) Restore(ctx context.Context, key any) error {
+	if tx.cnf.SoftDelete == "" {
+		return fmt.Errorf("soft delete is not configured for table %s", tx.cnf.Table)
+	}
+	key, err := tx.store().keyValue(key)
+	if err != nil {
+		return err
+	}
+	conds, args, err := tx.store().scope(ctx, []string{tx.store().pkWhere()}, tx.store().keyArgs(key))
+	if err != nil {
+		return err
+	}
+
+	before, err := tx.auditBefore(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	q := fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s", tx.cnf.Table, tx.cnf.SoftDelete, strings.Join(conds, " AND "))
+	tx.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", "Tx.Restore"), slog.String("q", q), slog.Any("key", key))
+	result, err := tx.shared.ExecContext(ctx, q, args...)
+	if err != nil {
+		return fmt.Errorf("cannot execute query: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("cannot read affected rows: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%w: %w", &MissingKeyError{key}, sql.ErrNoRows)
+	}
+	tx.shared.recordChange(ChangeEvent{Table: tx.cnf.Table, Key: key, Op: ChangePut})
+	return tx.auditAfter(ctx, AuditRestore, key, before)
+}
+
+func (tx *Tx[T]) hasDeleteHooks() bool {
+	var model T
+	_, before := any(&model).(HookBeforeDelete[T])
+	_, after := any(&model).(HookAfterDelete[T])
+	return before || after || len(tx.cnf.Hooks.BeforeDelete) > 0 || len(tx.cnf.Hooks.AfterDelete) > 0
+}
+
+// deleteWhere removes the rows matching the condition. If there are delete hooks or the audit
+// is enabled the rows are loaded first and removed one by one to process each of them.
+func (tx *Tx[T]) deleteWhere(ctx context.Context, method string, purge bool, where string, args ...interface{}) (int64, error) {
+	if !tx.hasDeleteHooks() && tx.cnf.Audit == "" {
+		return tx.execDelete(ctx, method, purge, where, args...)
+	}
+
+	s := tx.store()
+	s.withDeleted = purge
+	where, args, err := s.where(ctx, []string{where}, args)
+	if err != nil {
+		return 0, err
+	}
+	models, err := s.queryList(ctx, fmt.Sprintf("SELECT %s FROM %s%s", s.selectCols(), tx.cnf.Table, where), args...)
+	if err != nil {
+		return 0, err
+	}
+	return tx.deleteModels(ctx, method, purge, models)
+}
+
+// deleteModels removes each model running the delete hooks around it.
+func (tx *Tx[T]) deleteModels(ctx context.Context, method string, purge bool, models []*T) (int64, error) {
+	var deleted int64
+	for _, model := range models {
+		if err := runBeforeDelete(ctx, tx, tx.cnf.Hooks, model); err != nil {
+			return deleted, err
+		}
+		key := tx.key(model)
+		before, err := tx.auditBefore(ctx, key)
+		if err != nil {
+			return deleted, err
+		}
+		n, err := tx.execDelete(ctx, method, purge, tx.store().pkWhere(), tx.store().keyArgs(key)...)
+		if err != nil {
+			return deleted, err
+		}
+		if n == 0 {
+			continue
+		}
+		deleted += n
+		if tx.cnf.SoftDelete != "" && !purge {
+			// Soft deleted rows are still stored with the delete mark.
+			err = tx.auditAfter(ctx, AuditDelete, key, before)
+		} else {
+			err = tx.audit(ctx, AuditDelete, key, before, nil)
+		}
+		if err != nil {
+			return deleted, err
+		}
+		if err := runAfterDelete(ctx, tx, tx.cnf.Hooks, model); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// execDelete removes the rows matching the condition, or marks them as deleted if there is a
+// SoftDelete column configured and the rows are not being purged. The keys of the affected rows
+// are returned by the statement to report them as changes.
+func (tx *Tx[T]) execDelete(ctx context.Context, method string, purge bool, where string, args ...interface{}) (int64, error) {
+	conds, args, err := tx.store().scope(ctx, []string{where}, args)
+	if err != nil {
+		return 0, err
+	}
+	where = strings.Join(conds, " AND ")
+	q := fmt.Sprintf("DELETE FROM %s WHERE %s", tx.cnf.Table, where)
+	if tx.cnf.SoftDelete != "" && !purge {
+		q = fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s AND %s IS NULL", tx.cnf.Table, tx.cnf.SoftDelete, where, tx.cnf.SoftDelete)
+		args = append([]any{tx.cnf.Clock()}, args...)
+	}
+	q += " RETURNING " + strings.Join(tx.cnf.PrimaryKeys, ",")
+	tx.cnf.Logger.Log(ctx, levelTrace, "SQL", slog.String("method", method), slog.String("q", q))
+	rows, err := tx.shared.QueryContext(ctx, q, args...)
+	if err != nil {
+		return 0, fmt.Errorf("cannot execute query: %w", err)
+	}
+	defer rows.Close()
+
+	s := tx.store()
+	types := s.pkTypes()
+	var deleted int64
+	for rows.Next() {
+		dest := make([]any, len(types))
+		for i, t := range types {
+			dest[i] = reflect.New(t).Interface()
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return deleted, fmt.Errorf("cannot scan deleted key: %w", err)
+		}
+		key := make(Key, len(dest))
+		for i := range dest {
+			key[i] = reflect.ValueOf(dest[i]).Elem().Interface()
+		}
+		if s.composite() {
+			tx.shared.recordChange(ChangeEvent{Table: tx.cnf.Table, Key: key, Op: ChangeDelete})
+		} else {
+			tx.shared.recordChange(ChangeEvent{Table: tx.cnf.Table, Key: key[0], Op: ChangeDelete})
+		}
+		deleted++
+	}
+	if err := rows.Err(); err != nil {
+		return deleted, fmt.Errorf("cannot execute query: %w", err)
+	}
+	return deleted, nil
+}
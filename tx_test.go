@@ -0,0 +1,208 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxReadModifyWrite(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+	require.NoError(t, repo.Put(ctx, &testModel{Name: "counter", Value: "1"}))
+
+	tx, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	model, err := tx.Get(ctx, "counter")
+	require.NoError(t, err)
+	n, err := strconv.Atoi(model.Value)
+	require.NoError(t, err)
+	model.Value = strconv.Itoa(n + 1)
+	require.NoError(t, tx.Put(ctx, model))
+
+	require.NoError(t, tx.Commit())
+
+	model, err = repo.Get(ctx, "counter")
+	require.NoError(t, err)
+	require.Equal(t, model.Value, "2")
+}
+
+func TestTxGetNotFound(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	tx, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	model, err := tx.Get(ctx, "foo-name")
+	require.Nil(t, model)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	var missing *MissingKeyError
+	require.ErrorAs(t, err, &missing)
+	require.Equal(t, missing.Key, "foo-name")
+}
+
+func TestTxReadsSeeUncommittedWrites(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	repo, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+
+	tx, err := repo.BeginTx(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	require.NoError(t, tx.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, tx.Put(ctx, &testModel{Name: "bar-name", Value: "bar-value"}))
+
+	exists, err := tx.Exists(ctx, "foo-name")
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	count, err := tx.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, count, 2)
+
+	results, err := tx.GetMulti(ctx, []string{"bar-name", "foo-name"})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, results[0].Value, "bar-value")
+	require.Equal(t, results[1].Value, "foo-value")
+
+	model, err := tx.Query(ctx, "SELECT * FROM TestModels WHERE Value = ?", "bar-value")
+	require.NoError(t, err)
+	require.Equal(t, model.Name, "bar-name")
+
+	models, err := tx.QueryList(ctx, "SELECT * FROM TestModels ORDER BY Name")
+	require.NoError(t, err)
+	require.Len(t, models, 2)
+
+	require.NoError(t, tx.DeleteKey(ctx, "foo-name"))
+	_, err = tx.Exec(ctx, "UPDATE TestModels SET Value = ? WHERE Name = ?", "updated", "bar-name")
+	require.NoError(t, err)
+
+	require.NoError(t, tx.Commit())
+
+	results, err = repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, results[0].Value, "updated")
+}
+
+func TestTxSharedCommit(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	models, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+	children, err := NewRepoGeneric(db, RepoConfig[testChild]{
+		Table:      "TestChildren",
+		PrimaryKey: "ID",
+	})
+	require.NoError(t, err)
+
+	shared, err := BeginTx(ctx, db)
+	require.NoError(t, err)
+	defer shared.Rollback()
+
+	require.NoError(t, models.WithTx(shared).Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+	require.NoError(t, children.WithTx(shared).Put(ctx, &testChild{ID: "foo-child", Parent: "foo-name"}))
+
+	require.NoError(t, shared.Commit())
+
+	n, err := models.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+	n, err = children.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+}
+
+func TestTxSharedRollback(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	models, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+	children, err := NewRepoGeneric(db, RepoConfig[testChild]{
+		Table:      "TestChildren",
+		PrimaryKey: "ID",
+	})
+	require.NoError(t, err)
+
+	err = RunInTx(ctx, db, func(shared *SharedTx) error {
+		require.NoError(t, models.WithTx(shared).Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}))
+		require.NoError(t, children.WithTx(shared).Put(ctx, &testChild{ID: "foo-child", Parent: "foo-name"}))
+		return errors.New("failed")
+	})
+	require.EqualError(t, err, "failed")
+
+	n, err := models.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 0)
+	n, err = children.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 0)
+}
+
+func TestTxSharedFromRepoTx(t *testing.T) {
+	ctx := context.Background()
+	db := connectDB(t)
+	defer db.Close()
+
+	models, err := NewRepoGeneric(db, RepoConfig[testModel]{
+		Table:      "TestModels",
+		PrimaryKey: "Name",
+	})
+	require.NoError(t, err)
+	children, err := NewRepoGeneric(db, RepoConfig[testChild]{
+		Table:      "TestChildren",
+		PrimaryKey: "ID",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, models.RunInTx(ctx, func(tx *Tx[testModel]) error {
+		if err := tx.Put(ctx, &testModel{Name: "foo-name", Value: "foo-value"}); err != nil {
+			return err
+		}
+		return children.WithTx(tx.Shared()).Put(ctx, &testChild{ID: "foo-child", Parent: "foo-name"})
+	}))
+
+	n, err := children.Count(ctx)
+	require.NoError(t, err)
+	require.EqualValues(t, n, 1)
+}